@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/jackparsonss/vertex/engine"
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/codegen/utils"
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/jackparsonss/vertex/internal/constants"
+)
+
+// load reads the configuration and parses the annotated functions.
+func load(o *options) (*engine.Engine, types.Vertex, error) {
+	c, err := config.Load(o.config)
+	if err != nil {
+		return nil, types.Vertex{}, exit(EXIT_USAGE, err)
+	}
+
+	o.logf("input roots: %v\n", c.InputRoots)
+	e, err := engine.NewEngine(c)
+	if err != nil {
+		return nil, types.Vertex{}, exit(EXIT_PARSE, err)
+	}
+
+	v, err := e.Parse()
+	if err != nil {
+		return nil, types.Vertex{}, exit(EXIT_PARSE, err)
+	}
+
+	o.logf("found %d routes in %d packages\n", len(v.Functions), len(v.Packages))
+	return e, v, nil
+}
+
+// generate parses the annotated functions and writes the generated code.
+func generate(o *options) (*engine.Engine, error) {
+	e, v, err := load(o)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := e.Generate(v, o.dryRun)
+	for _, file := range files {
+		if o.dryRun {
+			fmt.Fprintf(o.stdout, "would write %s\n", file)
+		} else {
+			o.logf("wrote %s\n", file)
+		}
+	}
+
+	return e, exit(EXIT_GENERATE, err)
+}
+
+func runGenerate(o *options) error {
+	_, err := generate(o)
+	return err
+}
+
+func runRun(o *options) error {
+	e, err := generate(o)
+	if err != nil {
+		return err
+	}
+
+	if !e.Config.Generates(constants.MAIN_GENERATOR) {
+		return exit(EXIT_USAGE, fmt.Errorf("starting the server needs the %s generator", constants.MAIN_GENERATOR))
+	}
+
+	return exit(EXIT_RUNTIME, e.Run())
+}
+
+func runBuild(o *options) error {
+	e, err := generate(o)
+	if err != nil {
+		return err
+	}
+
+	if !e.Config.Generates(constants.MAIN_GENERATOR) {
+		return exit(EXIT_USAGE, fmt.Errorf("building the server needs the %s generator", constants.MAIN_GENERATOR))
+	}
+
+	output := o.output
+	if output == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		output = filepath.Base(wd)
+	}
+
+	o.logf("building %s\n", output)
+	return exit(EXIT_RUNTIME, e.Build(output))
+}
+
+func runCheck(o *options) error {
+	_, v, err := load(o)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.stdout, "%d routes ok\n", len(v.Functions))
+	return nil
+}
+
+func runRoutes(o *options) error {
+	_, v, err := load(o)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(o.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ROUTE\tFUNCTION\tMIDDLEWARE\tDECLARED AT")
+	for _, fn := range v.Functions {
+		middleware := "-"
+		if len(fn.Middleware) > 0 {
+			middleware = fmt.Sprint(fn.Middleware)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", utils.Route(fn.Method, fn.Path), functionName(fn), middleware, fn.Pos)
+	}
+
+	return w.Flush()
+}
+
+// functionName names the function serving a route, qualified with its
+// package and, for methods, its struct.
+func functionName(fn types.FunctionInfo) string {
+	if fn.IsMethod {
+		return fn.PackageAlias + "." + fn.StructName + "." + fn.Name
+	}
+
+	return fn.PackageAlias + "." + fn.Name
+}
+
+func runInit(o *options) error {
+	path := o.config.ConfigFile
+	if path == "" {
+		path = constants.CONFIG_FILE
+	}
+
+	if o.dryRun {
+		fmt.Fprint(o.stdout, config.Example)
+		return nil
+	}
+
+	if _, err := os.Stat(path); err == nil && !o.force {
+		return fmt.Errorf("%s already exists, use -force to overwrite it", path)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(config.Example), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.stdout, "wrote %s\n", path)
+	return nil
+}
+
+func runVersion(o *options) error {
+	fmt.Fprintf(o.stdout, "vertex %s\n", versionString())
+	return nil
+}
+
+// logf prints a step when running verbosely.
+func (o *options) logf(format string, args ...any) {
+	if o.verbose {
+		fmt.Fprintf(o.stderr, format, args...)
+	}
+}
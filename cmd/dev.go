@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/jackparsonss/vertex/engine"
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/codegen/utils"
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/jackparsonss/vertex/internal/constants"
+)
+
+// stopTimeout bounds how long a server is given to shut down after a signal
+// before it is killed.
+const stopTimeout = 10 * time.Second
+
+// runDev generates the code, starts the server and then watches the sources:
+// every change regenerates the code and rebuilds and restarts the server,
+// since the generated code also depends on files without @server directives,
+// such as those declaring constructors and the types in signatures. A failed
+// rebuild keeps the running server.
+func runDev(o *options) error {
+	c, err := config.Load(o.config)
+	if err != nil {
+		return exit(EXIT_USAGE, err)
+	}
+
+	if !c.Generates(constants.MAIN_GENERATOR) {
+		return exit(EXIT_USAGE, fmt.Errorf("starting the server needs the %s generator", constants.MAIN_GENERATOR))
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	binaries, err := os.MkdirTemp("", "vertex-dev-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(binaries)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := &engine.Engine{Config: c}
+	var routes []types.FunctionInfo
+	var server *devServer
+	var changes <-chan engine.Change
+	var change engine.Change
+	for build := 0; ; build++ {
+		// Generated files whose content is unchanged are not rewritten.
+		if v, err := compile(c); err != nil {
+			fmt.Fprintf(o.stderr, "vertex dev: %v\n", err)
+		} else {
+			if build > 0 {
+				printRouteDiff(o, routes, v.Functions)
+			}
+			routes = v.Functions
+		}
+
+		// Watch once the code is generated, so writing it is not a change.
+		if changes == nil {
+			changes = engine.NewWatcher(c).Watch(ctx, o.interval)
+		}
+
+		binary := filepath.Join(binaries, fmt.Sprintf("server-%d", build))
+		if runtime.GOOS == "windows" {
+			binary += ".exe"
+		}
+		if err := builder.Build(binary); err != nil {
+			fmt.Fprintf(o.stderr, "vertex dev: %v, waiting for changes\n", err)
+		} else {
+			if err := server.stop(os.Interrupt); err != nil {
+				o.logf("server stopped: %v\n", err)
+			}
+			if server, err = startServer(o, binary); err != nil {
+				fmt.Fprintf(o.stderr, "vertex dev: %v, waiting for changes\n", err)
+			}
+		}
+
+		for waiting := true; waiting; {
+			select {
+			case sig := <-signals:
+				return exit(EXIT_RUNTIME, server.stop(sig))
+			case err := <-server.exited():
+				if err != nil {
+					fmt.Fprintf(o.stderr, "vertex dev: server exited: %v, waiting for changes\n", err)
+				} else {
+					fmt.Fprintln(o.stderr, "vertex dev: server exited, waiting for changes")
+				}
+				server = nil
+			case change = <-changes:
+				waiting = false
+			}
+		}
+		o.logf("changed: %v\n", change.Files)
+	}
+}
+
+// compile parses the annotated functions and writes the generated code.
+func compile(c config.Config) (types.Vertex, error) {
+	e, err := engine.NewEngine(c)
+	if err != nil {
+		return types.Vertex{}, err
+	}
+
+	v, err := e.Parse()
+	if err != nil {
+		return types.Vertex{}, err
+	}
+
+	_, err = e.Generate(v, false)
+	return v, err
+}
+
+// devServer is a server process started by vertex dev.
+type devServer struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+func startServer(o *options, binary string) (*devServer, error) {
+	cmd := exec.Command(binary)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = o.stdout
+	cmd.Stderr = o.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	s := &devServer{cmd: cmd, done: make(chan error, 1)}
+	go func() {
+		s.done <- cmd.Wait()
+	}()
+
+	return s, nil
+}
+
+// exited returns a channel receiving the result of the process once it
+// exits, or nil when no server is running.
+func (s *devServer) exited() <-chan error {
+	if s == nil {
+		return nil
+	}
+
+	return s.done
+}
+
+// stop forwards sig to the server and waits for it to shut down, killing it
+// if it does not exit within stopTimeout.
+func (s *devServer) stop(sig os.Signal) error {
+	if s == nil {
+		return nil
+	}
+
+	if err := s.cmd.Process.Signal(sig); err != nil {
+		s.cmd.Process.Kill()
+	}
+
+	select {
+	case err := <-s.done:
+		return err
+	case <-time.After(stopTimeout):
+		s.cmd.Process.Kill()
+		return <-s.done
+	}
+}
+
+// printRouteDiff prints the routes added, removed and changed by a reload.
+func printRouteDiff(o *options, previous, current []types.FunctionInfo) {
+	diff := diffRoutes(previous, current)
+	if len(diff) == 0 {
+		fmt.Fprintln(o.stderr, "vertex dev: routes unchanged")
+		return
+	}
+
+	for _, line := range diff {
+		fmt.Fprintf(o.stderr, "vertex dev: %s\n", line)
+	}
+}
+
+// diffRoutes describes the routes added (+), removed (-) and changed (~)
+// between two parses, one line per route. A route changes when its function
+// or the signature, middleware or type arguments of its function do.
+func diffRoutes(previous, current []types.FunctionInfo) []string {
+	before := make(map[string]types.FunctionInfo)
+	for _, fn := range previous {
+		before[utils.Route(fn.Method, fn.Path)] = fn
+	}
+
+	var diff []string
+	after := make(map[string]bool)
+	for _, fn := range current {
+		route := utils.Route(fn.Method, fn.Path)
+		after[route] = true
+
+		prev, ok := before[route]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("+ %s %s", route, functionName(fn)))
+		case !sameFunction(prev, fn):
+			diff = append(diff, fmt.Sprintf("~ %s %s", route, functionName(fn)))
+		}
+	}
+
+	for _, fn := range previous {
+		if route := utils.Route(fn.Method, fn.Path); !after[route] {
+			diff = append(diff, fmt.Sprintf("- %s %s", route, functionName(fn)))
+		}
+	}
+
+	return diff
+}
+
+// sameFunction reports whether two parses of a route describe the same
+// function, ignoring where it is declared.
+func sameFunction(a, b types.FunctionInfo) bool {
+	a.Pos = b.Pos
+
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRoutes(t *testing.T) {
+	previous := []types.FunctionInfo{
+		{Name: "List", Path: "/users", Method: "GET", PackageAlias: "users"},
+		{Name: "Save", Path: "/users", Method: "POST", PackageAlias: "users", Params: []types.ParamInfo{{Name: "u", Type: "users.User"}}},
+		{Name: "Delete", Path: "/users/{id}", Method: "DELETE", PackageAlias: "users"},
+		{Name: "Get", Path: "/cats", Method: "GET", PackageAlias: "pets", StructName: "CatService", IsMethod: true},
+	}
+	current := []types.FunctionInfo{
+		{Name: "List", Path: "/users", Method: "GET", PackageAlias: "users", Pos: previous[0].Pos},
+		{Name: "Save", Path: "/users", Method: "POST", PackageAlias: "users", Params: []types.ParamInfo{{Name: "u", Type: "*users.User"}}},
+		{Name: "Get", Path: "/cats", Method: "GET", PackageAlias: "pets", StructName: "CatService", IsMethod: true, Middleware: []string{"auth"}},
+		{Name: "Find", Path: "/users/{id}", Method: "GET", PackageAlias: "users"},
+	}
+	current[0].Pos.Line = 12
+
+	assert.Equal(t, []string{
+		"~ POST /users users.Save",
+		"~ GET /cats pets.CatService.Get",
+		"+ GET /users/{id} users.Find",
+		"- DELETE /users/{id} users.Delete",
+	}, diffRoutes(previous, current))
+	assert.Empty(t, diffRoutes(previous, previous))
+}
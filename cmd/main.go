@@ -1,52 +1,261 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"runtime/debug"
+	"strings"
+	"time"
 
-	"github.com/jackparsonss/vertex/engine"
 	"github.com/jackparsonss/vertex/internal/config"
 )
 
 const (
-	RUN_COMMAND   = "run"
-	BUILD_COMMAND = "build"
+	GENERATE_COMMAND = "generate"
+	RUN_COMMAND      = "run"
+	DEV_COMMAND      = "dev"
+	BUILD_COMMAND    = "build"
+	CHECK_COMMAND    = "check"
+	ROUTES_COMMAND   = "routes"
+	INIT_COMMAND     = "init"
+	VERSION_COMMAND  = "version"
 )
 
-var validCommands = map[string]bool{
-	RUN_COMMAND:   true,
-	BUILD_COMMAND: true,
+// Exit codes distinguishing the stage that failed.
+const (
+	EXIT_FAILURE  = 1
+	EXIT_USAGE    = 2
+	EXIT_PARSE    = 3
+	EXIT_GENERATE = 4
+	EXIT_RUNTIME  = 5
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3", and
+// otherwise read from the module version vertex was installed at.
+var version = ""
+
+// options holds the flags shared by the commands.
+type options struct {
+	config   config.Flags
+	dryRun   bool
+	verbose  bool
+	output   string
+	force    bool
+	interval time.Duration
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+type command struct {
+	name    string
+	summary string
+	flags   func(*flag.FlagSet, *options)
+	run     func(*options) error
+}
+
+var commands = []command{
+	{
+		name:    GENERATE_COMMAND,
+		summary: "generate the server and client code for the @server functions",
+		flags:   withFlags(configFlags, dryRunFlag, verboseFlag),
+		run:     runGenerate,
+	},
+	{
+		name:    RUN_COMMAND,
+		summary: "generate the code and start the server",
+		flags:   withFlags(configFlags, verboseFlag),
+		run:     runRun,
+	},
+	{
+		name:    DEV_COMMAND,
+		summary: "start the server and regenerate and restart it when the sources change",
+		flags: withFlags(configFlags, verboseFlag, func(fs *flag.FlagSet, o *options) {
+			fs.DurationVar(&o.interval, "interval", 500*time.Millisecond, "how often the sources are checked for changes")
+		}),
+		run: runDev,
+	},
+	{
+		name:    BUILD_COMMAND,
+		summary: "generate the code and compile the server into a binary",
+		flags: withFlags(configFlags, verboseFlag, func(fs *flag.FlagSet, o *options) {
+			fs.StringVar(&o.output, "o", "", "path of the binary (default the name of the working directory)")
+		}),
+		run: runBuild,
+	},
+	{
+		name:    CHECK_COMMAND,
+		summary: "report problems with the @server functions without generating code",
+		flags:   withFlags(configFlags, verboseFlag),
+		run:     runCheck,
+	},
+	{
+		name:    ROUTES_COMMAND,
+		summary: "list the routes of the @server functions",
+		flags:   withFlags(configFlags),
+		run:     runRoutes,
+	},
+	{
+		name:    INIT_COMMAND,
+		summary: "write a vertex.yaml listing every setting",
+		flags: withFlags(dryRunFlag, func(fs *flag.FlagSet, o *options) {
+			fs.StringVar(&o.config.ConfigFile, "config", "", "path of the config file (default vertex.yaml)")
+			fs.BoolVar(&o.force, "force", false, "overwrite an existing config file")
+		}),
+		run: runInit,
+	},
+	{
+		name:    VERSION_COMMAND,
+		summary: "print the version of vertex",
+		flags:   withFlags(),
+		run:     runVersion,
+	},
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatalln("Must provide vertex with command, use 'vertex run' to start up")
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// execute runs the command named by the first argument and returns the exit
+// code.
+func execute(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return EXIT_USAGE
+		}
+		return 0
 	}
 
-	command := os.Args[1]
-	if ok := validCommands[command]; !ok {
-		log.Fatalln("Invalid command, use 'vertex run' to start up")
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "vertex: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return EXIT_USAGE
 	}
 
-	c, err := config.NewConfig("vertex", "vertex")
-	if err != nil {
-		log.Fatalf("Error creating config: %v\n", err)
+	o := &options{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: vertex %s [flags]\n\n%s.\n", cmd.name, capitalize(cmd.summary))
+		if hasFlags(fs) {
+			fmt.Fprintf(stderr, "\nFlags:\n")
+			fs.PrintDefaults()
+		}
 	}
+	cmd.flags(fs, o)
 
-	engine, err := engine.NewEngine(c)
-	if err != nil {
-		log.Fatalf("Error creating engine: %v\n", err)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return EXIT_USAGE
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "vertex %s: unexpected arguments %s\n", cmd.name, strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return EXIT_USAGE
 	}
 
-	err = engine.Compile()
-	if err != nil {
-		log.Fatalf("Error compiling: %v\n", err)
+	if err := cmd.run(o); err != nil {
+		fmt.Fprintf(stderr, "vertex %s: %v\n", cmd.name, err)
+
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			return exitErr.code
+		}
+		return EXIT_FAILURE
+	}
+
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Vertex turns annotated Go functions into an HTTP server and client.\n\nusage: vertex <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'vertex <command> -h' for the flags of a command.\n")
+	fmt.Fprintf(w, "\nExit codes: %d usage, %d parse, %d generate and %d runtime failures.\n", EXIT_USAGE, EXIT_PARSE, EXIT_GENERATE, EXIT_RUNTIME)
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
 	}
 
-	if command == RUN_COMMAND {
-		err = engine.Run()
-		if err != nil {
-			log.Fatalf("Error starting up: %v\n", err)
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func withFlags(flags ...func(*flag.FlagSet, *options)) func(*flag.FlagSet, *options) {
+	return func(fs *flag.FlagSet, o *options) {
+		for _, f := range flags {
+			f(fs, o)
 		}
 	}
 }
+
+func configFlags(fs *flag.FlagSet, o *options) {
+	o.config.Register(fs)
+}
+
+func dryRunFlag(fs *flag.FlagSet, o *options) {
+	fs.BoolVar(&o.dryRun, "dry-run", false, "print the files that would be written without writing them")
+}
+
+func verboseFlag(fs *flag.FlagSet, o *options) {
+	fs.BoolVar(&o.verbose, "verbose", false, "print the steps taken")
+}
+
+// exitError is a failure exiting with a code of its own.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func exit(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &exitError{code: code, err: err}
+}
+
+func versionString() string {
+	if version != "" {
+		return version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	return "(devel)"
+}
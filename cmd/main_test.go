@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+		expectedErr  string
+	}{
+		{name: "No command", args: nil, expectedCode: EXIT_USAGE, expectedErr: "usage: vertex <command> [flags]"},
+		{name: "Help", args: []string{"help"}, expectedCode: 0, expectedErr: "routes     list the routes"},
+		{name: "Unknown command", args: []string{"deploy"}, expectedCode: EXIT_USAGE, expectedErr: `unknown command "deploy"`},
+		{name: "Command help", args: []string{"generate", "-h"}, expectedCode: 0, expectedErr: "-dry-run"},
+		{name: "Unknown flag", args: []string{"check", "-nope"}, expectedCode: EXIT_USAGE, expectedErr: "flag provided but not defined: -nope"},
+		{name: "Extra arguments", args: []string{"version", "now"}, expectedCode: EXIT_USAGE, expectedErr: "unexpected arguments now"},
+		{name: "Invalid setting", args: []string{"check", "-pkg", "my-api"}, expectedCode: EXIT_USAGE, expectedErr: `-pkg: output.package: "my-api" is not a valid package name`},
+		{name: "Missing config file", args: []string{"routes", "-config", "missing.yaml"}, expectedCode: EXIT_USAGE, expectedErr: "open missing.yaml"},
+		{name: "Version", args: []string{"version"}, expectedCode: 0, expectedOut: "vertex "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := execute(tt.args, &stdout, &stderr)
+
+			assert.Equal(t, tt.expectedCode, code)
+			assert.Contains(t, stdout.String(), tt.expectedOut)
+			assert.Contains(t, stderr.String(), tt.expectedErr)
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vertex.yaml")
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, 0, execute([]string{"init", "-config", path, "-dry-run"}, &stdout, &stderr))
+	assert.Equal(t, config.Example, stdout.String())
+	assert.NoFileExists(t, path)
+
+	assert.Equal(t, 0, execute([]string{"init", "-config", path}, &stdout, &stderr))
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config.Example, string(content))
+
+	assert.Equal(t, EXIT_FAILURE, execute([]string{"init", "-config", path}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "already exists, use -force to overwrite it")
+	assert.Equal(t, 0, execute([]string{"init", "-config", path, "-force"}, &stdout, &stderr))
+}
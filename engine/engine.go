@@ -1,17 +1,25 @@
 package engine
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
-	"go/parser"
 	"go/token"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/jackparsonss/vertex/internal/codegen"
+	"github.com/jackparsonss/vertex/internal/codegen/gomod"
 	vp "github.com/jackparsonss/vertex/internal/codegen/parser"
+	"github.com/jackparsonss/vertex/internal/codegen/types"
 	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/jackparsonss/vertex/internal/constants"
+	"golang.org/x/tools/go/packages"
 )
 
 type Engine struct {
@@ -20,71 +28,174 @@ type Engine struct {
 }
 
 func NewEngine(config config.Config) (*Engine, error) {
-	nodes, err := getNodes(config.InputDir)
+	fset := token.NewFileSet()
+	pkgs, err := loadPackages(fset, config)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
-		return nil, err
-	}
-
-	return &Engine{vertexParser: vp.NewVertexParser(nodes, config), Config: config}, nil
+	return &Engine{vertexParser: vp.NewVertexParser(fset, pkgs, config), Config: config}, nil
 }
 
-func getNodes(root string) ([]*ast.File, error) {
-	fset := token.NewFileSet()
-	var astFiles []*ast.File
-
-	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+// loadPackages loads and type-checks the packages under the input roots for
+// the configured build tags, leaving out the output directory, the excluded
+// directories and the syntax of the files that are excluded or not included.
+// Test files and the vendor, testdata and hidden directories are left out by
+// the go command. Packages that fail to load are kept with their errors.
+func loadPackages(fset *token.FileSet, config config.Config) ([]*packages.Package, error) {
+	moduleRoot := filepath.Dir(config.GoModFile)
+
+	var patterns []string
+	for _, root := range config.InputRoots {
+		rel, err := filepath.Rel(moduleRoot, root)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if !d.IsDir() {
-			return nil
+		patterns = append(patterns, "./"+filepath.ToSlash(filepath.Join(rel, "...")))
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  moduleRoot,
+		Fset: fset,
+	}
+	if len(config.Tags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(config.Tags, ",")}
+	}
+	loaded, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var pkgs []*packages.Package
+	for _, pkg := range loaded {
+		if seen[pkg.ID] || len(pkg.GoFiles) == 0 {
+			continue
 		}
+		seen[pkg.ID] = true
 
-		pkgs, err := parser.ParseDir(fset, path, nil, parser.ParseComments)
+		dir := filepath.Dir(pkg.GoFiles[0])
+		rel, err := filepath.Rel(moduleRoot, dir)
 		if err != nil {
-			return fmt.Errorf("failed to parse dir %s: %w", path, err)
+			return nil, err
+		}
+		if config.OutputExcluded(dir) || excludedDir(config, rel) {
+			continue
 		}
 
-		for _, pkg := range pkgs {
-			for _, file := range pkg.Files {
-				astFiles = append(astFiles, file)
-			}
+		pkg.Syntax = slices.DeleteFunc(pkg.Syntax, func(node *ast.File) bool {
+			file := filepath.Join(rel, filepath.Base(fset.Position(node.Package).Filename))
+			return config.Excluded(file) || !config.Included(file)
+		})
+		if len(pkg.Syntax) == 0 {
+			continue
 		}
+		pkgs = append(pkgs, pkg)
+	}
 
-		return nil
-	})
+	return pkgs, nil
+}
+
+// excludedDir reports whether the directory at rel, relative to the module
+// root, or one of its parents is excluded.
+func excludedDir(config config.Config, rel string) bool {
+	for dir := rel; dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if config.Excluded(dir) {
+			return true
+		}
+	}
 
-	return astFiles, err
+	return false
 }
 
+// Compile parses the annotated functions and generates the code for them.
 func (e *Engine) Compile() error {
-	v, err := e.vertexParser.Parse()
+	v, err := e.Parse()
 	if err != nil {
 		return err
 	}
 
+	_, err = e.Generate(v, false)
+	return err
+}
+
+// Parse parses the annotated functions, printing the diagnostics to stderr.
+func (e *Engine) Parse() (types.Vertex, error) {
+	v, err := e.vertexParser.Parse()
+	for _, d := range e.vertexParser.Diagnostics() {
+		fmt.Fprintln(os.Stderr, d)
+	}
+
+	return v, err
+}
+
+// Generate writes the code of the enabled generators, removes the files
+// generated earlier by the disabled ones and tidies go.mod, and returns the
+// files written. A dry run renders the files without writing anything.
+func (e *Engine) Generate(v types.Vertex, dryRun bool) ([]string, error) {
+	if !dryRun {
+		if err := os.MkdirAll(e.Config.OutputDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
 	generator := codegen.NewGenerator(e.Config, v)
-	err = generator.GenerateServerCode()
-	if err != nil {
-		return err
+	generator.DryRun = dryRun
+
+	steps := []struct {
+		generator string
+		file      string
+		generate  func() error
+	}{
+		{constants.SERVER_GENERATOR, filepath.Join(e.Config.OutputDir, "server.go"), generator.GenerateServerCode},
+		{constants.CLIENT_GENERATOR, filepath.Join(e.Config.OutputDir, "client.go"), generator.GenerateClientCode},
+		{"", "", generator.GenerateRequestTypes},
+		{"", "", generator.GenerateRuntimeCode},
+		{constants.MAIN_GENERATOR, "main.go", generator.GenerateMain},
 	}
+	for _, step := range steps {
+		if step.generator != "" && !e.Config.Generates(step.generator) {
+			if !dryRun {
+				if err := removeGenerated(step.file); err != nil {
+					return generator.Files, err
+				}
+			}
+			continue
+		}
 
-	err = generator.GenerateClientCode()
-	if err != nil {
-		return err
+		if err := step.generate(); err != nil {
+			return generator.Files, err
+		}
+	}
+
+	if !dryRun {
+		if err := gomod.NewGoMod(e.Config.GoModFile).Tidy(); err != nil {
+			return generator.Files, err
+		}
 	}
 
-	err = generator.GenerateMain()
+	return generator.Files, nil
+}
+
+// removeGenerated removes a file left by a generator that has since been
+// disabled. Files without the generated header were written by hand and are
+// kept.
+func removeGenerated(file string) error {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if !bytes.HasPrefix(content, []byte(constants.GENERATED_HEADER)) {
+		return nil
+	}
+
+	return os.Remove(file)
 }
 
 func (e *Engine) Run() error {
@@ -98,3 +209,16 @@ func (e *Engine) Run() error {
 
 	return nil
 }
+
+// Build compiles the generated server into the binary at output.
+func (e *Engine) Build(output string) error {
+	cmd := exec.Command("go", "build", "-o", output, "./main.go")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("go build failed: %v", err)
+	}
+
+	return nil
+}
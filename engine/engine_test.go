@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/jackparsonss/vertex/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRemovesDisabledFiles(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	write("go.mod", "module example.com/app\n\ngo 1.25\n")
+	server := write("vertex/server.go", constants.GENERATED_HEADER+"\npackage vertex\n")
+	client := write("vertex/client.go", "package vertex\n")
+	main := write("main.go", "package main\n\nfunc main() {}\n")
+
+	e := &Engine{Config: config.Config{
+		GoModFile:         filepath.Join(root, "go.mod"),
+		OutputDir:         filepath.Join(root, "vertex"),
+		PackageNameOutput: "vertex",
+		Generators:        []string{constants.CLIENT_GENERATOR},
+	}}
+	files, err := e.Generate(types.Vertex{GoModPackage: "example.com/app"}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{client, filepath.Join(root, "vertex/requests.go"), filepath.Join(root, "vertex/runtime.go")}, files)
+	assert.NoFileExists(t, server)
+	assert.FileExists(t, main, "hand-written main.go is kept")
+
+	write("main.go", constants.GENERATED_HEADER+"\npackage main\n")
+	_, err = e.Generate(types.Vertex{GoModPackage: "example.com/app"}, true)
+	require.NoError(t, err)
+	assert.FileExists(t, main, "dry runs remove nothing")
+
+	_, err = e.Generate(types.Vertex{GoModPackage: "example.com/app"}, false)
+	require.NoError(t, err)
+	assert.NoFileExists(t, main)
+}
@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/jackparsonss/vertex/internal/constants"
+	"github.com/stretchr/testify/require"
+)
+
+// integrationSources is a module served by the generated code. Its package
+// and parameter names clash with the names the generated code declares, so
+// the generated package only compiles if it keeps them apart.
+var integrationSources = map[string]string{
+	"go.mod": "module example.com/app\n\ngo 1.25\n",
+	"config/config.go": `package config
+
+type Settings struct{ Greeting string }
+`,
+	"service/service.go": `package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/app/config"
+)
+
+// Events records the shutdown hooks and the closing of the Greeter.
+var Events []string
+
+type Greeter struct{ settings config.Settings }
+
+func NewGreeter(settings config.Settings) *Greeter { return &Greeter{settings: settings} }
+
+func (g *Greeter) Close() error {
+	Events = append(Events, "close")
+	return nil
+}
+
+// @server path=/greet/{name} method=GET middleware=auth,audit
+func (g *Greeter) Greet(ctx context.Context, name string, url string, path string) (string, error) {
+	return g.settings.Greeting + ", " + name + url + path, nil
+}
+
+// @server path=/echo method=POST
+func Echo(r string, w int, request []string, err bool) (string, int) {
+	if err {
+		return r, w + len(request)
+	}
+	return "", 0
+}
+
+// @server path=/pad method=POST
+func Pad(_id int, s string, a, A int) int { return _id + len(s) + a*A }
+
+// @server path=/configure method=POST
+func Configure(config config.Settings) string { return config.Greeting }
+
+// Counter is served from its zero value, though it is not a struct.
+type Counter int
+
+// @server path=/count method=GET
+func (c Counter) Count() int { return int(c) + 1 }
+
+// @server path=/timeout method=GET
+func Timeout(ctx context.Context) (time.Duration, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, errors.New("no deadline")
+	}
+	return time.Until(deadline), nil
+}
+`,
+	"e2e/e2e_test.go": `package e2e_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/app/config"
+	"example.com/app/service"
+	"example.com/app/vertex"
+)
+
+func TestGenerated(t *testing.T) {
+	settings := vertex.WithDependency(config.Settings{Greeting: "Hello"})
+	if err := vertex.RegisterRoutes(http.NewServeMux(), settings); err == nil || !strings.Contains(err.Error(), "unknown middleware \"auth\"") {
+		t.Fatalf("RegisterRoutes with unregistered middleware: got %v", err)
+	}
+
+	var order []string
+	record := func(name string) vertex.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	vertex.Use(record("global"))
+	vertex.RegisterMiddleware("auth", record("auth"))
+	vertex.RegisterMiddleware("audit", record("audit"))
+
+	server := httptest.NewServer(vertex.Handler(settings))
+	defer server.Close()
+	client := vertex.NewClient(server.URL, vertex.WithErrorHandler(func(err error) { t.Error(err) }))
+
+	greeting, err := client.Greet(context.Background(), "Ada", "?", "/")
+	if err != nil || greeting != "Hello, Ada?/" {
+		t.Errorf("Greet: got %q, %v", greeting, err)
+	}
+	if want := []string{"global", "auth", "audit"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("middleware order: got %v, want %v", order, want)
+	}
+
+	if r, w := client.Echo("r", 1, []string{"a", "b"}, true); r != "r" || w != 3 {
+		t.Errorf("Echo: got %q, %d", r, w)
+	}
+	if sum := client.Pad(40, "ab", 2, 3); sum != 48 {
+		t.Errorf("Pad: got %d", sum)
+	}
+	if greeting := client.Configure(config.Settings{Greeting: "Hi"}); greeting != "Hi" {
+		t.Errorf("Configure: got %q", greeting)
+	}
+
+	if count := client.Count(); count != 1 {
+		t.Errorf("Count: got %d", count)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if timeout, err := client.Timeout(ctx); err != nil || timeout <= 0 || timeout > time.Minute {
+		t.Errorf("Timeout: got %v, %v", timeout, err)
+	}
+
+	routed := vertex.NewClient("http://127.0.0.1:1", vertex.WithServiceURL("service", server.URL))
+	if greeting, err := routed.Greet(context.Background(), "Bob", "", ""); err != nil || greeting != "Hello, Bob" {
+		t.Errorf("Greet with a service URL: got %q, %v", greeting, err)
+	}
+
+	service.Events = nil
+	hook := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			service.Events = append(service.Events, name)
+			return nil
+		}
+	}
+	s, err := vertex.NewServer(settings, vertex.OnShutdown(hook("first"), hook("second")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"second", "first", "close"}; !reflect.DeepEqual(service.Events, want) {
+		t.Errorf("shutdown order: got %v, want %v", service.Events, want)
+	}
+}
+`,
+}
+
+func TestGeneratedCode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and tests a generated module")
+	}
+
+	root := t.TempDir()
+	for name, content := range integrationSources {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	t.Chdir(root)
+
+	c, err := config.NewConfig(config.Settings{Generators: []string{constants.SERVER_GENERATOR, constants.CLIENT_GENERATOR}})
+	require.NoError(t, err)
+
+	e, err := NewEngine(c)
+	require.NoError(t, err)
+	v, err := e.Parse()
+	require.NoError(t, err)
+	_, err = e.Generate(v, false)
+	require.NoError(t, err)
+
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "%s", output)
+}
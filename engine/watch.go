@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackparsonss/vertex/internal/config"
+)
+
+// Change lists the source files that changed between two polls of a
+// Watcher.
+type Change struct {
+	Files []string
+}
+
+// Watcher polls the Go source files under the input roots for changes,
+// selecting them like loadPackages does.
+type Watcher struct {
+	config config.Config
+	files  map[string]fileState
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher returns a watcher holding the current state of the sources.
+func NewWatcher(config config.Config) *Watcher {
+	w := &Watcher{config: config, files: make(map[string]fileState)}
+	w.Poll()
+
+	return w
+}
+
+// Watch polls the sources every interval until ctx is done. Changes are
+// debounced: they are sent once a poll finds no further changes, merged into
+// one Change.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) <-chan Change {
+	changes := make(chan Change)
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var pending Change
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if change := w.Poll(); len(change.Files) > 0 {
+				pending.Files = append(pending.Files, change.Files...)
+				continue
+			}
+			if len(pending.Files) == 0 {
+				continue
+			}
+
+			slices.Sort(pending.Files)
+			pending.Files = slices.Compact(pending.Files)
+			select {
+			case changes <- pending:
+			case <-ctx.Done():
+				return
+			}
+			pending = Change{}
+		}
+	}()
+
+	return changes
+}
+
+// Poll returns the files created, modified or removed since the last poll.
+func (w *Watcher) Poll() Change {
+	var change Change
+	seen := make(map[string]bool)
+	for file, info := range w.sources() {
+		seen[file] = true
+
+		old, ok := w.files[file]
+		if ok && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			continue
+		}
+
+		w.files[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		change.Files = append(change.Files, file)
+	}
+
+	for file := range w.files {
+		if !seen[file] {
+			delete(w.files, file)
+			change.Files = append(change.Files, file)
+		}
+	}
+
+	slices.Sort(change.Files)
+	return change
+}
+
+// sources returns the Go files under the input roots with their info,
+// leaving out test files, the vendor, testdata and hidden directories, the
+// output directory and the files that are excluded or not included.
+func (w *Watcher) sources() map[string]fs.FileInfo {
+	moduleRoot := filepath.Dir(w.config.GoModFile)
+
+	files := make(map[string]fs.FileInfo)
+	for _, root := range w.config.InputRoots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			rel, err := filepath.Rel(moduleRoot, path)
+			if err != nil {
+				return nil
+			}
+
+			name := d.Name()
+			if d.IsDir() {
+				if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				if w.config.OutputExcluded(path) || excludedDir(w.config, rel) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+				return nil
+			}
+			if w.config.Excluded(rel) || !w.config.Included(rel) {
+				return nil
+			}
+
+			if info, err := d.Info(); err == nil {
+				files[path] = info
+			}
+			return nil
+		})
+	}
+
+	return files
+}
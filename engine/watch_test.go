@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Duration(len(content))*time.Second)))
+		return path
+	}
+
+	api := write("api/users.go", "package api\n\n// @server path=/users\nfunc List() {}\n")
+	helpers := write("api/helpers.go", "package api\n")
+	w := NewWatcher(config.Config{
+		InputRoots: []string{root},
+		GoModFile:  filepath.Join(root, "go.mod"),
+		OutputDir:  filepath.Join(root, "vertex"),
+		Exclude:    []string{"legacy"},
+	})
+	assert.Len(t, w.files, 2)
+
+	write("api/users_test.go", "package api\n")
+	write("api/testdata/fixture.go", "package fixture\n")
+	write(".cache/cache.go", "package cache\n")
+	write("vertex/server.go", "package vertex\n")
+	write("legacy/old.go", "package legacy\n")
+	write("api/README.md", "@server\n")
+	assert.Equal(t, Change{}, w.Poll())
+
+	write("api/helpers.go", "package api\n\nfunc helper() {}\n")
+	assert.Equal(t, Change{Files: []string{helpers}}, w.Poll())
+
+	require.NoError(t, os.Remove(api))
+	assert.Equal(t, Change{Files: []string{api}}, w.Poll())
+
+	added := write("billing/billing.go", "package billing\n\n// @server path=/invoices\nfunc Invoices() {}\n")
+	assert.Equal(t, Change{Files: []string{added}}, w.Poll())
+	assert.Equal(t, Change{}, w.Poll())
+}
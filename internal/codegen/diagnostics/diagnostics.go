@@ -0,0 +1,83 @@
+package diagnostics
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"slices"
+)
+
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+
+	return "warning"
+}
+
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if !d.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+type Diagnostics []Diagnostic
+
+func (ds *Diagnostics) Errorf(pos token.Position, format string, args ...any) {
+	*ds = append(*ds, Diagnostic{Pos: pos, Severity: Error, Message: fmt.Sprintf(format, args...)})
+}
+
+func (ds *Diagnostics) Warnf(pos token.Position, format string, args ...any) {
+	*ds = append(*ds, Diagnostic{Pos: pos, Severity: Warning, Message: fmt.Sprintf(format, args...)})
+}
+
+// Sort orders the diagnostics by file and position.
+func (ds Diagnostics) Sort() {
+	slices.SortStableFunc(ds, func(a, b Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.Pos.Filename, b.Pos.Filename),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+}
+
+func (ds Diagnostics) ErrorCount() int {
+	count := 0
+	for _, d := range ds {
+		if d.Severity == Error {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Err returns an error summarising the error diagnostics, or nil if there
+// are only warnings.
+func (ds Diagnostics) Err() error {
+	count := ds.ErrorCount()
+	switch count {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("found 1 error in @server directives")
+	default:
+		return fmt.Errorf("found %d errors in @server directives", count)
+	}
+}
@@ -0,0 +1,47 @@
+package diagnostics
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticString(t *testing.T) {
+	pos := token.Position{Filename: "users.go", Line: 12, Column: 1}
+
+	assert.Equal(t, "users.go:12:1: error: unsupported method FETCH", Diagnostic{Pos: pos, Severity: Error, Message: "unsupported method FETCH"}.String())
+	assert.Equal(t, "warning: unknown directive @sever", Diagnostic{Severity: Warning, Message: "unknown directive @sever"}.String())
+}
+
+func TestErr(t *testing.T) {
+	var ds Diagnostics
+	assert.NoError(t, ds.Err())
+
+	ds.Warnf(token.Position{}, "unused")
+	assert.NoError(t, ds.Err())
+
+	ds.Errorf(token.Position{}, "missing path")
+	assert.EqualError(t, ds.Err(), "found 1 error in @server directives")
+
+	ds.Errorf(token.Position{}, "unsupported method %s", "FETCH")
+	assert.EqualError(t, ds.Err(), "found 2 errors in @server directives")
+	assert.Equal(t, 2, ds.ErrorCount())
+}
+
+func TestSort(t *testing.T) {
+	ds := Diagnostics{
+		{Pos: token.Position{Filename: "b.go", Line: 1, Column: 1}, Message: "b"},
+		{Pos: token.Position{Filename: "a.go", Line: 9, Column: 1}, Message: "a9"},
+		{Pos: token.Position{Filename: "a.go", Line: 2, Column: 5}, Message: "a2:5"},
+		{Pos: token.Position{Filename: "a.go", Line: 2, Column: 1}, Message: "a2:1"},
+	}
+	ds.Sort()
+
+	var messages []string
+	for _, d := range ds {
+		messages = append(messages, d.Message)
+	}
+
+	assert.Equal(t, []string{"a2:1", "a2:5", "a9", "b"}, messages)
+}
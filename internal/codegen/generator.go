@@ -4,21 +4,49 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
-	"html/template"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/codegen/utils"
 	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/jackparsonss/vertex/internal/constants"
 	"golang.org/x/tools/imports"
 )
 
 //go:embed templates/*.tmpl
 var templates embed.FS
 
+var funcMap = template.FuncMap{
+	"signature":            signature,
+	"returnValues":         returnValues,
+	"usesBody":             usesBody,
+	"requestMethod":        requestMethod,
+	"route":                route,
+	"arguments":            arguments,
+	"typeArguments":        typeArguments,
+	"serviceField":         serviceField,
+	"pathParams":           pathParams,
+	"bodyParams":           bodyParams,
+	"requestType":          requestType,
+	"fieldType":            fieldType,
+	"constructorArguments": constructorArguments,
+}
+
 type Generator struct {
 	Config config.Config
 	Vertex types.Vertex
+
+	// DryRun renders the files without writing them.
+	DryRun bool
+
+	// Files lists the files rendered so far.
+	Files []string
 }
 
 func NewGenerator(config config.Config, v types.Vertex) *Generator {
@@ -28,16 +56,22 @@ func NewGenerator(config config.Config, v types.Vertex) *Generator {
 func (g *Generator) GenerateMain() error {
 	tmpl := template.Must(template.ParseFS(templates, "templates/main.tmpl"))
 
+	rel, err := filepath.Rel(filepath.Dir(g.Config.GoModFile), g.Config.OutputDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("output directory %s is outside the module", g.Config.OutputDir)
+	}
+
+	importPath := path.Join(g.Vertex.GoModPackage, filepath.ToSlash(rel))
 	templateData := struct {
-		GoModPackage string
+		Package    string
+		Alias      string
+		ImportPath string
 	}{
-		GoModPackage: g.Vertex.GoModPackage,
+		Package:    g.Config.PackageNameOutput,
+		ImportPath: importPath,
 	}
-
-	var buf bytes.Buffer
-	err := tmpl.Execute(&buf, templateData)
-	if err != nil {
-		return err
+	if path.Base(importPath) != g.Config.PackageNameOutput {
+		templateData.Alias = g.Config.PackageNameOutput
 	}
 
 	p, err := filepath.Abs(".")
@@ -45,113 +79,252 @@ func (g *Generator) GenerateMain() error {
 		return err
 	}
 
-	filename := fmt.Sprintf("%s/main.go", p)
-	formattedFile, err := imports.Process(filename, buf.Bytes(), nil)
-	if err != nil {
-		return err
+	return g.render(tmpl, fmt.Sprintf("%s/main.go", p), templateData)
+}
+
+func (g *Generator) GenerateClientCode() error {
+	tmpl := template.Must(template.New("client.tmpl").Funcs(funcMap).ParseFS(templates, "templates/client.tmpl"))
+
+	templateData := struct {
+		Package   string
+		BaseURL   string
+		Packages  []types.Package
+		Functions []types.FunctionInfo
+	}{
+		Package:   g.Config.PackageNameOutput,
+		BaseURL:   g.Config.ClientBaseURL,
+		Packages:  g.Vertex.Packages,
+		Functions: g.Vertex.Functions,
 	}
 
-	err = os.WriteFile(filename, formattedFile, 0644)
-	if err != nil {
-		return err
+	return g.render(tmpl, fmt.Sprintf("%s/client.go", g.Config.OutputDir), templateData)
+}
+
+func (g *Generator) GenerateServerCode() error {
+	tmpl := template.Must(template.New("server.tmpl").Funcs(funcMap).ParseFS(templates, "templates/server.tmpl"))
+
+	templateData := struct {
+		Package   string
+		Addr      string
+		Packages  []types.Package
+		Services  []types.Service
+		Functions []types.FunctionInfo
+	}{
+		Package:   g.Config.PackageNameOutput,
+		Addr:      g.Config.ServerAddr,
+		Packages:  g.Vertex.Packages,
+		Services:  g.Vertex.Services,
+		Functions: g.Vertex.Functions,
 	}
 
-	return nil
+	return g.render(tmpl, fmt.Sprintf("%s/server.go", g.Config.OutputDir), templateData)
 }
 
-func (g *Generator) GenerateClientCode() error {
-	tmpl := template.Must(template.ParseFS(templates, "templates/client.tmpl"))
+func (g *Generator) GenerateRequestTypes() error {
+	tmpl := template.Must(template.New("requests.tmpl").Funcs(funcMap).ParseFS(templates, "templates/requests.tmpl"))
 
-	packageName := ""
-	if len(g.Vertex.Functions) > 0 {
-		packageName = g.Vertex.Functions[0].PackageName
+	templateData := struct {
+		Package   string
+		Packages  []types.Package
+		Functions []types.FunctionInfo
+	}{
+		Package:   g.Config.PackageNameOutput,
+		Packages:  g.Vertex.Packages,
+		Functions: g.Vertex.Functions,
 	}
 
+	return g.render(tmpl, fmt.Sprintf("%s/requests.go", g.Config.OutputDir), templateData)
+}
+
+func (g *Generator) GenerateRuntimeCode() error {
+	tmpl := template.Must(template.ParseFS(templates, "templates/runtime.tmpl"))
+
 	templateData := struct {
-		PackageName  string
-		Functions    []types.FunctionInfo
-		GoModPackage string
+		Package string
 	}{
-		PackageName:  packageName,
-		Functions:    g.Vertex.Functions,
-		GoModPackage: g.Vertex.GoModPackage,
+		Package: g.Config.PackageNameOutput,
 	}
 
-	var buf bytes.Buffer
-	err := tmpl.Execute(&buf, templateData)
-	if err != nil {
-		return err
+	return g.render(tmpl, fmt.Sprintf("%s/runtime.go", g.Config.OutputDir), templateData)
+}
+
+// signature renders the result list of a client function, mirroring the
+// original function's return values.
+func signature(fn types.FunctionInfo) string {
+	results := make([]string, 0, len(fn.Returns)+1)
+	for _, r := range fn.Returns {
+		results = append(results, r.Type)
 	}
 
-	filename := fmt.Sprintf("%s/client.go", g.Config.OutputDir)
-	formattedFile, err := imports.Process(filename, buf.Bytes(), nil)
-	if err != nil {
-		return err
+	if fn.ReturnsError {
+		results = append(results, "error")
 	}
 
-	err = os.WriteFile(filename, formattedFile, 0644)
-	if err != nil {
-		return err
+	switch len(results) {
+	case 0:
+		return ""
+	case 1:
+		return results[0]
 	}
 
-	return nil
+	return "(" + strings.Join(results, ", ") + ")"
 }
 
-func (g *Generator) GenerateServerCode() error {
-	tmpl := template.Must(template.ParseFS(templates, "templates/server.tmpl"))
+// returnValues renders the variables holding the results of a function,
+// using errExpr as the trailing error value when it has one.
+func returnValues(fn types.FunctionInfo, errExpr string) string {
+	values := make([]string, 0, len(fn.Returns)+1)
+	for i := range fn.Returns {
+		values = append(values, fmt.Sprintf("vxResult%d", i))
+	}
+
+	if fn.ReturnsError {
+		values = append(values, errExpr)
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// usesBody reports whether a function's parameters are sent as a JSON
+// request body rather than in the query string.
+func usesBody(fn types.FunctionInfo) bool {
+	return utils.MethodHasBody(fn.Method)
+}
+
+// requestMethod returns the method used by the client to call a function.
+// Method-agnostic endpoints are called with POST.
+func requestMethod(fn types.FunctionInfo) string {
+	if fn.Method == constants.ANY_METHOD {
+		return constants.DEFAULT_METHOD
+	}
+
+	return fn.Method
+}
+
+// route returns the ServeMux pattern a function's handler is registered
+// under. Registering GET endpoints also serves HEAD requests for them.
+func route(fn types.FunctionInfo) string {
+	return utils.Route(fn.Method, fn.Path)
+}
+
+// arguments renders the arguments a function is called with, passing ctx as
+// the context and spreading the slice bound to a variadic parameter.
+func arguments(fn types.FunctionInfo, ctx string) string {
+	args := make([]string, 0, len(fn.Params)+1)
+	if fn.HasContext {
+		args = append(args, ctx)
+	}
+
+	for _, param := range fn.Params {
+		args = append(args, param.Ident)
+	}
+
+	if isVariadic(fn) {
+		args[len(args)-1] += "..."
+	}
+
+	return strings.Join(args, ", ")
+}
+
+// typeArguments renders the type arguments a handler instantiates a generic
+// function with, or nothing for other functions.
+func typeArguments(fn types.FunctionInfo) string {
+	if len(fn.TypeArgs) == 0 {
+		return ""
+	}
+
+	return "[" + strings.Join(fn.TypeArgs, ", ") + "]"
+}
 
-	packageName := ""
-	if len(g.Vertex.Functions) > 0 {
-		packageName = g.Vertex.Functions[0].PackageName
+// serviceField names the field of the generated services struct holding the
+// instance of a served struct.
+func serviceField(packageAlias, structName string) string {
+	return lowerFirst(packageAlias) + structName
+}
+
+// constructorArguments returns the arguments a service constructor is called
+// with: the background context and the dependencies looked up for it.
+func constructorArguments(constructor types.Constructor) string {
+	var args []string
+	if constructor.HasContext {
+		args = append(args, "context.Background()")
+	}
+	for i := range constructor.Params {
+		args = append(args, fmt.Sprintf("vxArg%d", i))
 	}
 
-	structFuncs := make(map[string][]types.FunctionInfo)
-	var standaloneFuncs []types.FunctionInfo
+	return strings.Join(args, ", ")
+}
 
-	for _, fn := range g.Vertex.Functions {
-		if fn.IsMethod {
-			structFuncs[fn.StructName] = append(structFuncs[fn.StructName], fn)
-		} else {
-			standaloneFuncs = append(standaloneFuncs, fn)
+// pathParams returns the parameters bound from wildcards in the path.
+func pathParams(fn types.FunctionInfo) []types.ParamInfo {
+	var params []types.ParamInfo
+	for _, param := range fn.Params {
+		if param.InPath {
+			params = append(params, param)
 		}
 	}
 
-	allFunctions := make([]types.FunctionInfo, 0)
-	for _, fns := range structFuncs {
-		allFunctions = append(allFunctions, fns...)
+	return params
+}
+
+// bodyParams returns the parameters sent in the query string or request
+// body, that is, those not bound from the path.
+func bodyParams(fn types.FunctionInfo) []types.ParamInfo {
+	var params []types.ParamInfo
+	for _, param := range fn.Params {
+		if !param.InPath {
+			params = append(params, param)
+		}
 	}
-	allFunctions = append(allFunctions, standaloneFuncs...)
 
-	templateData := struct {
-		PackageName     string
-		StructFuncs     map[string][]types.FunctionInfo
-		StandaloneFuncs []types.FunctionInfo
-		AllFunctions    []types.FunctionInfo
-		GoModPackage    string
-	}{
-		PackageName:     packageName,
-		StructFuncs:     structFuncs,
-		StandaloneFuncs: standaloneFuncs,
-		AllFunctions:    allFunctions,
-		GoModPackage:    g.Vertex.GoModPackage,
+	return params
+}
+
+func isVariadic(fn types.FunctionInfo) bool {
+	return len(fn.Params) > 0 && strings.HasPrefix(fn.Params[len(fn.Params)-1].Type, "...")
+}
+
+// requestType names the struct holding a function's JSON request body.
+func requestType(fn types.FunctionInfo) string {
+	return lowerFirst(fn.Ident) + "Request"
+}
+
+// fieldType converts a variadic parameter type to the equivalent slice type.
+func fieldType(paramType string) string {
+	if rest, ok := strings.CutPrefix(paramType, "..."); ok {
+		return "[]" + rest
 	}
 
+	return paramType
+}
+
+func lowerFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+func (g *Generator) render(tmpl *template.Template, filename string, data any) error {
 	var buf bytes.Buffer
-	err := tmpl.Execute(&buf, templateData)
+	err := tmpl.Execute(&buf, data)
 	if err != nil {
 		return err
 	}
 
-	filename := fmt.Sprintf("%s/server.go", g.Config.OutputDir)
 	formattedFile, err := imports.Process(filename, buf.Bytes(), nil)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(filename, formattedFile, 0644)
-	if err != nil {
-		return err
+	g.Files = append(g.Files, filename)
+	if g.DryRun {
+		return nil
+	}
+
+	// Leave unchanged files alone, so tools watching them do not see a change.
+	if existing, err := os.ReadFile(filename); err == nil && bytes.Equal(existing, formattedFile) {
+		return nil
 	}
 
-	return nil
+	return os.WriteFile(filename, formattedFile, 0644)
 }
@@ -0,0 +1,149 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       types.FunctionInfo
+		expected string
+	}{
+		{
+			name:     "No return values",
+			fn:       types.FunctionInfo{},
+			expected: "",
+		},
+		{
+			name:     "Single value",
+			fn:       types.FunctionInfo{Returns: []types.ReturnInfo{{Type: "*pkg.User"}}},
+			expected: "*pkg.User",
+		},
+		{
+			name:     "Error only",
+			fn:       types.FunctionInfo{ReturnsError: true},
+			expected: "error",
+		},
+		{
+			name: "Multiple values and error",
+			fn: types.FunctionInfo{
+				Returns:      []types.ReturnInfo{{Type: "int"}, {Type: "bool"}},
+				ReturnsError: true,
+			},
+			expected: "(int, bool, error)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, signature(tt.fn))
+		})
+	}
+}
+
+func TestReturnValues(t *testing.T) {
+	fn := types.FunctionInfo{Returns: []types.ReturnInfo{{Type: "int"}, {Type: "bool"}}}
+	assert.Equal(t, "vxResult0, vxResult1", returnValues(fn, "vxErr"))
+
+	fn.ReturnsError = true
+	assert.Equal(t, "vxResult0, vxResult1, vxErr", returnValues(fn, "vxErr"))
+	assert.Equal(t, "nil", returnValues(types.FunctionInfo{ReturnsError: true}, "nil"))
+}
+
+func TestRequestFields(t *testing.T) {
+	assert.Equal(t, "getUserRequest", requestType(types.FunctionInfo{Name: "GetUser", Ident: "GetUser"}))
+	assert.Equal(t, "usersGetUserRequest", requestType(types.FunctionInfo{Name: "GetUser", Ident: "UsersGetUser"}))
+	assert.Equal(t, "[]string", fieldType("...string"))
+	assert.Equal(t, "map[string]int", fieldType("map[string]int"))
+	assert.True(t, isVariadic(types.FunctionInfo{Params: []types.ParamInfo{{Name: "ids", Type: "...int"}}}))
+	assert.False(t, isVariadic(types.FunctionInfo{Params: []types.ParamInfo{{Name: "ids", Type: "[]int"}}}))
+}
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		method          string
+		expectedRoute   string
+		expectedRequest string
+		expectedBody    bool
+	}{
+		{method: "GET", expectedRoute: "GET /items/{id}", expectedRequest: "GET", expectedBody: false},
+		{method: "POST", expectedRoute: "POST /items/{id}", expectedRequest: "POST", expectedBody: true},
+		{method: "PUT", expectedRoute: "PUT /items/{id}", expectedRequest: "PUT", expectedBody: true},
+		{method: "PATCH", expectedRoute: "PATCH /items/{id}", expectedRequest: "PATCH", expectedBody: true},
+		{method: "DELETE", expectedRoute: "DELETE /items/{id}", expectedRequest: "DELETE", expectedBody: false},
+		{method: "HEAD", expectedRoute: "HEAD /items/{id}", expectedRequest: "HEAD", expectedBody: false},
+		{method: "ANY", expectedRoute: "/items/{id}", expectedRequest: "POST", expectedBody: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			fn := types.FunctionInfo{Method: tt.method, Path: "/items/{id}"}
+
+			assert.Equal(t, tt.expectedRoute, route(fn))
+			assert.Equal(t, tt.expectedRequest, requestMethod(fn))
+			assert.Equal(t, tt.expectedBody, usesBody(fn))
+		})
+	}
+}
+
+func TestArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       types.FunctionInfo
+		expected string
+	}{
+		{
+			name:     "No parameters",
+			fn:       types.FunctionInfo{},
+			expected: "",
+		},
+		{
+			name:     "Context only",
+			fn:       types.FunctionInfo{HasContext: true},
+			expected: "vxCtx",
+		},
+		{
+			name: "Context and parameters",
+			fn: types.FunctionInfo{
+				HasContext: true,
+				Params:     []types.ParamInfo{{Name: "id", Type: "int", Ident: "id"}, {Name: "url", Type: "string", Ident: "url_"}},
+			},
+			expected: "vxCtx, id, url_",
+		},
+		{
+			name:     "Variadic",
+			fn:       types.FunctionInfo{Params: []types.ParamInfo{{Name: "prefix", Type: "string", Ident: "prefix"}, {Name: "ids", Type: "...int", Ident: "ids"}}},
+			expected: "prefix, ids...",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, arguments(tt.fn, "vxCtx"))
+		})
+	}
+}
+
+func TestTypeArguments(t *testing.T) {
+	assert.Equal(t, "", typeArguments(types.FunctionInfo{}))
+	assert.Equal(t, "[string, map[string]users.Pair[int, bool]]", typeArguments(types.FunctionInfo{
+		TypeArgs: []string{"string", "map[string]users.Pair[int, bool]"},
+	}))
+}
+
+func TestServiceField(t *testing.T) {
+	assert.Equal(t, "usersUserService", serviceField("users", "UserService"))
+	assert.Equal(t, "serviceAUserService", serviceField("ServiceA", "UserService"))
+}
+
+func TestConstructorArguments(t *testing.T) {
+	assert.Equal(t, "", constructorArguments(types.Constructor{}))
+	assert.Equal(t, "context.Background(), vxArg0, vxArg1", constructorArguments(types.Constructor{
+		HasContext: true,
+		Params:     []types.ParamInfo{{Name: "db", Type: "*sql.DB"}, {Name: "config", Type: "users.Config"}},
+	}))
+}
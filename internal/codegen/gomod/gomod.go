@@ -6,7 +6,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
@@ -43,35 +42,6 @@ func ParseGoModule(goModPath string) (string, error) {
 	return "", fmt.Errorf("no module declaration found in %s", goModPath)
 }
 
-func (gm *GoMod) AddReplace() error {
-	content, err := os.ReadFile(gm.path)
-	if err != nil {
-		return err
-	}
-
-	contentStr := string(content)
-
-	replacePattern := regexp.MustCompile(`(?m)^replace\s+vertex\s*=>\s*\./vertex`)
-	if replacePattern.MatchString(contentStr) {
-		return nil
-	}
-
-	file, err := os.OpenFile(gm.path, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	if len(contentStr) > 0 && !strings.HasSuffix(contentStr, "\n") {
-		if _, err := file.WriteString("\n"); err != nil {
-			return err
-		}
-	}
-
-	_, err = file.WriteString("\nreplace vertex => ./vertex\n")
-	return err
-}
-
 func (gm *GoMod) Tidy() error {
 	dir := filepath.Dir(gm.path)
 
@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/jackparsonss/vertex/internal/constants"
+)
+
+// directiveKeyPattern matches a value that is actually the next key of a
+// directive, as in "path= method=GET".
+var directiveKeyPattern = regexp.MustCompile(`^\w+=`)
+
+var keyPattern = regexp.MustCompile(`^\w+$`)
+
+// directivePattern matches @server where it starts a word and is followed by
+// whitespace or the end of the comment, so that addresses such as
+// ops@server.example.com and words such as @serverless are not directives.
+var directivePattern = regexp.MustCompile(`(?:^|\W)(` + regexp.QuoteMeta(constants.SERVER_DIRECTIVE) + `)(?:\s|$)`)
+
+// annotationPattern matches anything that looks like a directive, so that
+// misspellings of @server can be reported. Like directivePattern, it leaves
+// out the @ of e-mail addresses.
+var annotationPattern = regexp.MustCompile(`(?:^|[^\w.@])(@(\w+))`)
+
+var directiveKeys = []string{
+	strings.TrimSuffix(constants.PATH_DIRECTIVE, "="),
+	strings.TrimSuffix(constants.METHOD_DIRECTIVE, "="),
+	strings.TrimSuffix(constants.MIDDLEWARE_DIRECTIVE, "="),
+	strings.TrimSuffix(constants.INSTANTIATE_DIRECTIVE, "="),
+}
+
+// serverDirective holds the settings of the @server directive of a function.
+type serverDirective struct {
+	Path       string
+	Method     string
+	Middleware []string
+
+	// TypeArgs are the type arguments a generic function is served with.
+	TypeArgs []string
+}
+
+type directiveField struct {
+	Key    string
+	Value  string
+	Offset int
+}
+
+type directiveToken struct {
+	Text   string
+	Offset int
+}
+
+// directive is a parsed @server comment. Offsets are byte offsets into the
+// comment text, used to report positions.
+type directive struct {
+	Offset int
+	Fields []directiveField
+	Strays []directiveToken
+}
+
+// parseDirective parses the key=value pairs following @server in a comment.
+// Whitespace is allowed around the '=' of each pair.
+func parseDirective(text string) (directive, bool) {
+	match := directivePattern.FindStringSubmatchIndex(text)
+	if match == nil {
+		return directive{}, false
+	}
+
+	start := match[2]
+	d := directive{Offset: start}
+	rest := start + len(constants.SERVER_DIRECTIVE)
+	tokens := tokenize(text[rest:], rest)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		key, value, ok := strings.Cut(token.Text, "=")
+		if !ok && i+1 < len(tokens) && strings.HasPrefix(tokens[i+1].Text, "=") {
+			value, ok = tokens[i+1].Text[1:], true
+			i++
+		}
+
+		if !ok || !keyPattern.MatchString(key) {
+			d.Strays = append(d.Strays, token)
+			continue
+		}
+
+		if value == "" && i+1 < len(tokens) && !isKey(tokens, i+1) {
+			value = tokens[i+1].Text
+			i++
+		}
+
+		d.Fields = append(d.Fields, directiveField{Key: key, Value: value, Offset: token.Offset})
+	}
+
+	return d, true
+}
+
+// isKey reports whether tokens[i] starts a new key=value pair.
+func isKey(tokens []directiveToken, i int) bool {
+	if directiveKeyPattern.MatchString(tokens[i].Text) {
+		return true
+	}
+
+	return keyPattern.MatchString(tokens[i].Text) && i+1 < len(tokens) && strings.HasPrefix(tokens[i+1].Text, "=")
+}
+
+func tokenize(text string, offset int) []directiveToken {
+	var tokens []directiveToken
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				tokens = append(tokens, directiveToken{Text: text[start:i], Offset: offset + start})
+				start = -1
+			}
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, directiveToken{Text: text[start:], Offset: offset + start})
+	}
+
+	return tokens
+}
+
+// closest returns the candidate closest to word if it is likely to be a
+// misspelling of it.
+func closest(word string, candidates []string) (string, bool) {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(word), candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+
+	return best, best != ""
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
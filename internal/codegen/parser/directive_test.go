@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		name           string
+		text           string
+		expectedOk     bool
+		expectedFields []directiveField
+		expectedStrays []directiveToken
+	}{
+		{
+			name:       "Not a directive",
+			text:       "// GetUser fetches a user",
+			expectedOk: false,
+		},
+		{
+			name:       "E-mail address",
+			text:       "// Send mails the report to ops@server.example.com.",
+			expectedOk: false,
+		},
+		{
+			name:       "Word starting with the directive",
+			text:       "// runs on @serverless infrastructure",
+			expectedOk: false,
+		},
+		{
+			name:       "Directive ending the comment",
+			text:       "// @server",
+			expectedOk: true,
+		},
+		{
+			name:       "Directive without a space after the slashes",
+			text:       "//@server path=/users",
+			expectedOk: true,
+			expectedFields: []directiveField{
+				{Key: "path", Value: "/users", Offset: 10},
+			},
+		},
+		{
+			name:       "Key value pairs",
+			text:       "// @server path=/users method=GET",
+			expectedOk: true,
+			expectedFields: []directiveField{
+				{Key: "path", Value: "/users", Offset: 11},
+				{Key: "method", Value: "GET", Offset: 23},
+			},
+		},
+		{
+			name:       "Whitespace around equals",
+			text:       "// @server path = /users method =GET",
+			expectedOk: true,
+			expectedFields: []directiveField{
+				{Key: "path", Value: "/users", Offset: 11},
+				{Key: "method", Value: "GET", Offset: 25},
+			},
+		},
+		{
+			name:       "Empty values",
+			text:       "// @server path= method=",
+			expectedOk: true,
+			expectedFields: []directiveField{
+				{Key: "path", Value: "", Offset: 11},
+				{Key: "method", Value: "", Offset: 17},
+			},
+		},
+		{
+			name:       "Value containing equals",
+			text:       "// @server path=/users?sort=desc",
+			expectedOk: true,
+			expectedFields: []directiveField{
+				{Key: "path", Value: "/users?sort=desc", Offset: 11},
+			},
+		},
+		{
+			name:       "Stray tokens",
+			text:       "// @server GET path=/users",
+			expectedOk: true,
+			expectedFields: []directiveField{
+				{Key: "path", Value: "/users", Offset: 15},
+			},
+			expectedStrays: []directiveToken{
+				{Text: "GET", Offset: 11},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := parseDirective(tt.text)
+
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedFields, d.Fields)
+			assert.Equal(t, tt.expectedStrays, d.Strays)
+		})
+	}
+}
+
+func TestClosest(t *testing.T) {
+	keys := []string{"path", "method"}
+
+	suggestion, ok := closest("mehtod", keys)
+	assert.True(t, ok)
+	assert.Equal(t, "method", suggestion)
+
+	suggestion, ok = closest("Path", keys)
+	assert.True(t, ok)
+	assert.Equal(t, "path", suggestion)
+
+	_, ok = closest("middleware", keys)
+	assert.False(t, ok)
+
+	assert.Equal(t, 0, editDistance("server", "server"))
+	assert.Equal(t, 1, editDistance("sever", "server"))
+	assert.Equal(t, 2, editDistance("mehtod", "method"))
+}
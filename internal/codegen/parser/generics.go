@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"strings"
+)
+
+// instantiate checks that a function can be served with concrete types. A
+// generic function is instantiated with the type arguments of the
+// instantiate key of its directive, evaluated in the scope of its file, and
+// the instantiated signature is recorded for signature. It returns the type
+// arguments as the generated code spells them, and false when the function
+// cannot be served.
+func (v *VertexParser) instantiate(fn *ast.FuncDecl, typeArgs []string) ([]string, bool) {
+	obj, ok := v.funcs[fn]
+	if !ok {
+		return nil, true
+	}
+
+	name, pos := fn.Name.Name, v.position(fn.Pos())
+	sig := obj.Signature()
+	if sig.RecvTypeParams().Len() > 0 {
+		v.diagnostics.Errorf(pos, "%s: methods of generic types cannot be served, since the generated code cannot choose their type arguments", name)
+		return nil, false
+	}
+
+	tparams := sig.TypeParams()
+	switch {
+	case tparams.Len() == 0 && len(typeArgs) == 0:
+		return nil, true
+	case tparams.Len() == 0:
+		v.diagnostics.Errorf(pos, "%s: instantiate is set in the @server directive, but the function is not generic", name)
+		return nil, false
+	case len(typeArgs) == 0:
+		names := make([]string, tparams.Len())
+		for i := range names {
+			names[i] = tparams.At(i).Obj().Name()
+		}
+		v.diagnostics.Errorf(pos, "%s: generic functions cannot be served without type arguments, add instantiate=%s with concrete types to the @server directive", name, strings.Join(names, ","))
+		return nil, false
+	case len(typeArgs) != tparams.Len():
+		v.diagnostics.Errorf(pos, "%s: instantiate lists %d type arguments, but the function has %d type parameters", name, len(typeArgs), tparams.Len())
+		return nil, false
+	}
+
+	targs := make([]gotypes.Type, len(typeArgs))
+	for i, expr := range typeArgs {
+		tv, err := gotypes.Eval(token.NewFileSet(), obj.Pkg(), fn.Pos(), expr)
+		if err != nil {
+			v.diagnostics.Errorf(pos, "%s: invalid type argument %s: %v", name, expr, err)
+			return nil, false
+		}
+		if !tv.IsType() {
+			v.diagnostics.Errorf(pos, "%s: type argument %s is not a type", name, expr)
+			return nil, false
+		}
+		targs[i] = tv.Type
+	}
+
+	inst, err := gotypes.Instantiate(nil, sig, targs, true)
+	if err != nil {
+		v.diagnostics.Errorf(pos, "%s: cannot instantiate with %s: %v", name, strings.Join(typeArgs, ", "), err)
+		return nil, false
+	}
+	v.instances[fn] = inst.(*gotypes.Signature)
+
+	spelled := make([]string, len(targs))
+	for i, t := range targs {
+		v.checkReferable(pos, name, "type argument "+typeArgs[i], t)
+		spelled[i] = v.typeString(t)
+	}
+
+	return spelled, true
+}
+
+// splitTypeArgs splits the comma-separated type arguments of an instantiate
+// key, leaving the commas nested in brackets, parentheses and braces, as in
+// map[string]Pair[int,string] or func(int,int), to the types holding them.
+func splitTypeArgs(value string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range value {
+		switch r {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(args, value[start:])
+}
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstantiate(t *testing.T) {
+	tests := []struct {
+		name             string
+		code             string
+		expectNil        bool
+		expectedTypeArgs []string
+		expectedParams   []types.ParamInfo
+		expectedReturns  []types.ReturnInfo
+		expectedErrs     []string
+	}{
+		{
+			name: "Instantiated generic types",
+			code: `
+				type Page[T any] struct{ Items []T }
+				type User struct{}
+
+				// @server path=/users method=POST
+				func ListUsers(pages map[string]Page[User]) (Page[*User], error) { return Page[*User]{}, nil }
+			`,
+			expectedParams:  []types.ParamInfo{{Name: "pages", Type: "map[string]testpkg.Page[testpkg.User]"}},
+			expectedReturns: []types.ReturnInfo{{Type: "testpkg.Page[*testpkg.User]"}},
+		},
+		{
+			name: "Generic function with type arguments",
+			code: `
+				type Pair[K comparable, V any] struct{ Key K; Value V }
+				type User struct{}
+
+				// @server path=/pairs method=POST instantiate=string,map[string]Pair[int,User]
+				func MakePair[K comparable, V any](key K, value V) Pair[K, V] { return Pair[K, V]{key, value} }
+			`,
+			expectedTypeArgs: []string{"string", "map[string]testpkg.Pair[int, testpkg.User]"},
+			expectedParams:   []types.ParamInfo{{Name: "key", Type: "string"}, {Name: "value", Type: "map[string]testpkg.Pair[int, testpkg.User]"}},
+			expectedReturns:  []types.ReturnInfo{{Type: "testpkg.Pair[string, map[string]testpkg.Pair[int, testpkg.User]]"}},
+		},
+		{
+			name: "Type argument from an import",
+			code: `
+				// @server path=/wait method=GET instantiate=time.Duration
+				func Wait[T ~int64](d T) T { _ = time.Second; return d }
+			`,
+			expectedTypeArgs: []string{"time.Duration"},
+			expectedParams:   []types.ParamInfo{{Name: "d", Type: "time.Duration"}},
+			expectedReturns:  []types.ReturnInfo{{Type: "time.Duration"}},
+		},
+		{
+			name: "Generic function without type arguments",
+			code: `
+				// @server path=/pairs method=POST
+				func MakePair[K comparable, V any](key K, value V) {}
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"MakePair: generic functions cannot be served without type arguments, add instantiate=K,V with concrete types to the @server directive"},
+		},
+		{
+			name: "Wrong number of type arguments",
+			code: `
+				// @server path=/pairs method=POST instantiate=int
+				func MakePair[K comparable, V any](key K, value V) {}
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"MakePair: instantiate lists 1 type arguments, but the function has 2 type parameters"},
+		},
+		{
+			name: "Unknown type argument",
+			code: `
+				// @server path=/items method=POST instantiate=Item
+				func Save[T any](item T) {}
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"Save: invalid type argument Item: eval:1:1: undefined: Item"},
+		},
+		{
+			name: "Type argument that is not a type",
+			code: `
+				const Limit = 10
+
+				// @server path=/items method=POST instantiate=Limit
+				func Save[T any](item T) {}
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"Save: type argument Limit is not a type"},
+		},
+		{
+			name: "Unsatisfied constraint",
+			code: `
+				// @server path=/items method=POST instantiate=string
+				func Sum[T ~int | ~float64](values ...T) T { var sum T; return sum }
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"Sum: cannot instantiate with string: string does not satisfy ~int | ~float64 (string missing in ~int | ~float64)"},
+		},
+		{
+			name: "Type arguments of a function that is not generic",
+			code: `
+				// @server path=/items method=POST instantiate=int
+				func Save(item int) {}
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"Save: instantiate is set in the @server directive, but the function is not generic"},
+		},
+		{
+			name: "Empty type argument",
+			code: `
+				// @server path=/items method=POST instantiate=int,
+				func Save[K, V any](key K, value V) {}
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"Save: empty type argument in @server directive"},
+		},
+		{
+			name: "Method of a generic type",
+			code: `
+				type Store[T any] struct{ items []T }
+
+				// @server path=/items method=GET
+				func (s *Store[T]) List() []T { return s.items }
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"List: methods of generic types cannot be served, since the generated code cannot choose their type arguments"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vp, decl := parseFunctionCode(t, tt.code)
+			fn := vp.parseFunction(decl, "testpkg")
+
+			var errs []string
+			for _, d := range vp.diagnostics {
+				errs = append(errs, d.Message)
+			}
+			assert.Equal(t, tt.expectedErrs, errs)
+
+			if tt.expectNil {
+				assert.Nil(t, fn)
+				return
+			}
+
+			assert.NotNil(t, fn)
+			assert.Equal(t, tt.expectedTypeArgs, fn.TypeArgs)
+			assert.Equal(t, tt.expectedParams, fn.Params)
+			assert.Equal(t, tt.expectedReturns, fn.Returns)
+		})
+	}
+}
+
+func TestSplitTypeArgs(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{value: "int", expected: []string{"int"}},
+		{value: "int,string", expected: []string{"int", "string"}},
+		{value: "map[string]Pair[int,bool],func(int,int)", expected: []string{"map[string]Pair[int,bool]", "func(int,int)"}},
+		{value: "struct{A,B int},int", expected: []string{"struct{A,B int}", "int"}},
+		{value: "int,", expected: []string{"int", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitTypeArgs(tt.value))
+		})
+	}
+}
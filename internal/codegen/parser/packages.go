@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"fmt"
+	gotypes "go/types"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"golang.org/x/tools/go/packages"
+)
+
+// reservedAliases are the packages imported by the generated files, by name.
+// Other packages with the same name are imported under a different alias.
+var reservedAliases = map[string]string{
+	"bytes":    "bytes",
+	"context":  "context",
+	"encoding": "encoding",
+	"errors":   "errors",
+	"fmt":      "fmt",
+	"http":     "net/http",
+	"io":       "io",
+	"json":     "encoding/json",
+	"log":      "log",
+	"net":      "net",
+	"os":       "os",
+	"reflect":  "reflect",
+	"signal":   "os/signal",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"syscall":  "syscall",
+	"time":     "time",
+	"tls":      "crypto/tls",
+	"url":      "net/url",
+}
+
+// resolvePackages gives each parsed package an alias that is unique within
+// the generated code. Packages are visited by import path so the aliases do
+// not depend on the order they were loaded in.
+func (v *VertexParser) resolvePackages() {
+	pkgs := slices.Clone(v.pkgs)
+	slices.SortFunc(pkgs, func(a, b *packages.Package) int {
+		return strings.Compare(a.PkgPath, b.PkgPath)
+	})
+
+	for _, pkg := range pkgs {
+		v.packageOf(pkg.Types)
+	}
+}
+
+// packageOf returns the import of a package in the generated code, picking
+// an alias for it the first time it is seen.
+func (v *VertexParser) packageOf(p *gotypes.Package) types.Package {
+	if pkg, ok := v.packages[p.Path()]; ok {
+		return pkg
+	}
+
+	pkg := types.Package{Name: p.Name(), ImportPath: p.Path()}
+	pkg.Alias = packageAlias(pkg, v.taken)
+	v.taken[pkg.Alias] = true
+	v.packages[pkg.ImportPath] = pkg
+	return pkg
+}
+
+// typeString renders t as the generated code spells it, qualifying named
+// types with the alias of their package and recording the package as
+// referenced so it is imported.
+func (v *VertexParser) typeString(t gotypes.Type) string {
+	return gotypes.TypeString(t, func(p *gotypes.Package) string {
+		pkg := v.packageOf(p)
+		v.referenced[pkg.ImportPath] = pkg
+		return pkg.Alias
+	})
+}
+
+// packageAlias picks the first free name out of the package name, the
+// package name prefixed with its parent directories, and the package name
+// followed by a number, leaving out the names the generated package declares.
+func packageAlias(pkg types.Package, taken map[string]bool) string {
+	free := func(alias string) bool {
+		reserved, ok := reservedAliases[alias]
+		return !taken[alias] && !generatedIdent(alias) && (!ok || reserved == pkg.ImportPath)
+	}
+
+	alias := pkg.Name
+	if free(alias) {
+		return alias
+	}
+
+	dir := path.Dir(pkg.ImportPath)
+	for dir != "." && dir != "/" {
+		alias = sanitizeAlias(path.Base(dir)) + alias
+		if free(alias) {
+			return alias
+		}
+
+		dir = path.Dir(dir)
+	}
+
+	for i := 2; ; i++ {
+		if alias := fmt.Sprintf("%s%d", pkg.Name, i); free(alias) {
+			return alias
+		}
+	}
+}
+
+// sanitizeAlias drops the characters of a directory name that cannot appear
+// in an identifier.
+func sanitizeAlias(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+
+		return -1
+	}, name)
+}
+
+// usedPackages returns the packages declaring the given functions together
+// with the referenced packages their types are declared in, sorted by import
+// path.
+func usedPackages(functions []types.FunctionInfo, referenced map[string]types.Package) []types.Package {
+	seen := make(map[string]bool)
+	var packages []types.Package
+	for _, fn := range functions {
+		if seen[fn.ImportPath] {
+			continue
+		}
+
+		seen[fn.ImportPath] = true
+		packages = append(packages, types.Package{Name: fn.PackageName, Alias: fn.PackageAlias, ImportPath: fn.ImportPath})
+	}
+
+	for importPath, pkg := range referenced {
+		if !seen[importPath] {
+			seen[importPath] = true
+			packages = append(packages, pkg)
+		}
+	}
+
+	slices.SortFunc(packages, func(a, b types.Package) int {
+		return strings.Compare(a.ImportPath, b.ImportPath)
+	})
+
+	return packages
+}
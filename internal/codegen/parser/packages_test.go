@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestResolvePackages(t *testing.T) {
+	var pkgs []*packages.Package
+	for _, p := range []struct{ name, importPath string }{
+		{"serviceB", "example.com/app/serviceB"},
+		{"serviceA", "example.com/app/serviceA"},
+		{"billing", "example.com/app/internal/billing"},
+		{"users", "example.com/app/shop/users"},
+		{"users", "example.com/app/admin/users"},
+		{"http", "example.com/app/api/http"},
+		{"app", "example.com/app"},
+	} {
+		pkgs = append(pkgs, &packages.Package{Name: p.name, PkgPath: p.importPath, Types: gotypes.NewPackage(p.importPath, p.name)})
+	}
+
+	vp := NewVertexParser(nil, pkgs, config.Config{})
+	vp.resolvePackages()
+
+	var got []types.Package
+	for _, pkg := range pkgs {
+		got = append(got, vp.packageOf(pkg.Types))
+	}
+
+	assert.Equal(t, []types.Package{
+		{Name: "serviceB", Alias: "serviceB", ImportPath: "example.com/app/serviceB"},
+		{Name: "serviceA", Alias: "serviceA", ImportPath: "example.com/app/serviceA"},
+		{Name: "billing", Alias: "billing", ImportPath: "example.com/app/internal/billing"},
+		{Name: "users", Alias: "shopusers", ImportPath: "example.com/app/shop/users"},
+		{Name: "users", Alias: "users", ImportPath: "example.com/app/admin/users"},
+		{Name: "http", Alias: "apihttp", ImportPath: "example.com/app/api/http"},
+		{Name: "app", Alias: "app", ImportPath: "example.com/app"},
+	}, got)
+
+	assert.Equal(t, types.Package{Name: "http", Alias: "http", ImportPath: "net/http"}, vp.packageOf(gotypes.NewPackage("net/http", "http")))
+	assert.Empty(t, vp.referenced)
+}
+
+func TestTypeString(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg := loadTestPackage(t, fset, "example.com/app/users",
+		testFile{"users.go", `package users
+
+import dbsql "database/sql"
+
+// @server path=/users method=POST
+func Save(id UserID, at Stamp, db *dbsql.DB, owner Owner) {}
+`},
+		testFile{"types.go", `package users
+
+import "time"
+
+type UserID int
+
+type Stamp = time.Time
+
+type Owner struct{ Name string }
+`},
+	)
+	require.Empty(t, pkg.Errors)
+
+	vp := NewVertexParser(nil, []*packages.Package{pkg}, config.Config{})
+	vp.resolvePackages()
+
+	var got []string
+	for param := range vp.signature(pkg.Syntax[0].Decls[1].(*ast.FuncDecl)).Params().Variables() {
+		got = append(got, vp.typeString(param.Type()))
+	}
+
+	assert.Equal(t, []string{"users.UserID", "users.Stamp", "*sql.DB", "users.Owner"}, got)
+	assert.Equal(t, []types.Package{
+		{Name: "sql", Alias: "sql", ImportPath: "database/sql"},
+		{Name: "users", Alias: "users", ImportPath: "example.com/app/users"},
+	}, usedPackages(nil, vp.referenced))
+}
+
+func TestPackageAlias(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkg      types.Package
+		taken    map[string]bool
+		expected string
+	}{
+		{
+			name:     "package name",
+			pkg:      types.Package{Name: "users", ImportPath: "example.com/app/users"},
+			expected: "users",
+		},
+		{
+			name:     "parent directory",
+			pkg:      types.Package{Name: "users", ImportPath: "example.com/app/shop/users"},
+			taken:    map[string]bool{"users": true},
+			expected: "shopusers",
+		},
+		{
+			name:     "invalid characters in parent directory",
+			pkg:      types.Package{Name: "users", ImportPath: "example.com/app/my-shop/users"},
+			taken:    map[string]bool{"users": true},
+			expected: "myshopusers",
+		},
+		{
+			name:     "reserved name",
+			pkg:      types.Package{Name: "json", ImportPath: "example.com/app/json"},
+			expected: "appjson",
+		},
+		{
+			name:     "standard library package with a reserved name",
+			pkg:      types.Package{Name: "json", ImportPath: "encoding/json"},
+			expected: "json",
+		},
+		{
+			name:     "name declared by the generated package",
+			pkg:      types.Package{Name: "handlers", ImportPath: "example.com/app/handlers"},
+			expected: "apphandlers",
+		},
+		{
+			name:     "numbered",
+			pkg:      types.Package{Name: "users", ImportPath: "users"},
+			taken:    map[string]bool{"users": true},
+			expected: "users2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, packageAlias(tt.pkg, tt.taken))
+		})
+	}
+}
+
+func TestUsedPackages(t *testing.T) {
+	functions := []types.FunctionInfo{
+		{PackageName: "users", PackageAlias: "shopusers", ImportPath: "example.com/app/shop/users"},
+		{PackageName: "billing", PackageAlias: "billing", ImportPath: "example.com/app/internal/billing"},
+		{PackageName: "users", PackageAlias: "shopusers", ImportPath: "example.com/app/shop/users"},
+	}
+
+	referenced := map[string]types.Package{
+		"database/sql":               {Name: "sql", Alias: "sql", ImportPath: "database/sql"},
+		"example.com/app/shop/users": {Name: "users", Alias: "shopusers", ImportPath: "example.com/app/shop/users"},
+	}
+
+	assert.Equal(t, []types.Package{
+		{Name: "sql", Alias: "sql", ImportPath: "database/sql"},
+		{Name: "billing", Alias: "billing", ImportPath: "example.com/app/internal/billing"},
+		{Name: "users", Alias: "shopusers", ImportPath: "example.com/app/shop/users"},
+	}, usedPackages(functions, referenced))
+}
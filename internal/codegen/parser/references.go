@@ -0,0 +1,224 @@
+package parser
+
+import (
+	"fmt"
+	"go/token"
+	gotypes "go/types"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"golang.org/x/tools/go/packages"
+)
+
+// outputPath returns the import path of the generated package, or an empty
+// string when the output directory is not known.
+func (v *VertexParser) outputPath(module string) string {
+	if v.config.OutputDir == "" || v.config.GoModFile == "" {
+		return ""
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(v.config.GoModFile), v.config.OutputDir)
+	if err != nil {
+		return ""
+	}
+
+	return path.Join(module, filepath.ToSlash(rel))
+}
+
+// checkSignature records an error for every type of a served function the
+// generated package cannot refer to.
+func (v *VertexParser) checkSignature(pos token.Position, name string, sig *gotypes.Signature, params []types.ParamInfo) {
+	if recv := sig.Recv(); recv != nil {
+		v.checkReferable(pos, name, "receiver", recv.Type())
+	}
+
+	for i, t := range v.paramTypes(sig) {
+		if i < len(params) {
+			v.checkReferable(pos, name, "parameter "+params[i].Name, t)
+		}
+	}
+
+	for i := range sig.Results().Len() {
+		v.checkReferable(pos, name, fmt.Sprintf("result %d", i+1), sig.Results().At(i).Type())
+	}
+}
+
+// checkReferable records an error when the generated package cannot spell t,
+// because it is or contains a named type that is unexported, declared in
+// package main, or declared in an internal package the generated package may
+// not import. what describes where the type appears, such as "parameter id".
+func (v *VertexParser) checkReferable(pos token.Position, name, what string, t gotypes.Type) {
+	if reason := v.unreferable(t, make(map[gotypes.Type]bool)); reason != "" {
+		v.diagnostics.Errorf(pos, "%s: %s of type %s cannot be used by the generated code: %s", name, what, v.typeString(t), reason)
+	}
+}
+
+// unreferable returns why the generated package cannot spell t, or an empty
+// string if it can.
+func (v *VertexParser) unreferable(t gotypes.Type, seen map[gotypes.Type]bool) string {
+	if seen[t] {
+		return ""
+	}
+	seen[t] = true
+
+	switch t := t.(type) {
+	case *gotypes.Alias:
+		if reason := v.unreferableName(t.Obj()); reason != "" {
+			return reason
+		}
+		for targ := range t.TypeArgs().Types() {
+			if reason := v.unreferable(targ, seen); reason != "" {
+				return reason
+			}
+		}
+	case *gotypes.Named:
+		if reason := v.unreferableName(t.Obj()); reason != "" {
+			return reason
+		}
+		for targ := range t.TypeArgs().Types() {
+			if reason := v.unreferable(targ, seen); reason != "" {
+				return reason
+			}
+		}
+	case *gotypes.Pointer:
+		return v.unreferable(t.Elem(), seen)
+	case *gotypes.Slice:
+		return v.unreferable(t.Elem(), seen)
+	case *gotypes.Array:
+		return v.unreferable(t.Elem(), seen)
+	case *gotypes.Chan:
+		return v.unreferable(t.Elem(), seen)
+	case *gotypes.Map:
+		if reason := v.unreferable(t.Key(), seen); reason != "" {
+			return reason
+		}
+		return v.unreferable(t.Elem(), seen)
+	case *gotypes.Signature:
+		for _, tuple := range []*gotypes.Tuple{t.Params(), t.Results()} {
+			for param := range tuple.Variables() {
+				if reason := v.unreferable(param.Type(), seen); reason != "" {
+					return reason
+				}
+			}
+		}
+	case *gotypes.Struct:
+		for field := range t.Fields() {
+			if reason := v.unreferable(field.Type(), seen); reason != "" {
+				return reason
+			}
+		}
+	case *gotypes.Interface:
+		for method := range t.ExplicitMethods() {
+			if reason := v.unreferable(method.Type(), seen); reason != "" {
+				return reason
+			}
+		}
+		for embedded := range t.EmbeddedTypes() {
+			if reason := v.unreferable(embedded, seen); reason != "" {
+				return reason
+			}
+		}
+	}
+
+	return ""
+}
+
+// unreferableName returns why the generated package cannot refer to the
+// declared type, or an empty string if it can.
+func (v *VertexParser) unreferableName(obj *gotypes.TypeName) string {
+	pkg := obj.Pkg()
+	if pkg == nil {
+		return ""
+	}
+
+	switch {
+	case obj.Parent() != nil && obj.Parent() != pkg.Scope():
+		return fmt.Sprintf("%s is declared inside a function", obj.Name())
+	case !obj.Exported():
+		return fmt.Sprintf("%s is unexported", obj.Name())
+	case pkg.Name() == "main":
+		return fmt.Sprintf("%s is declared in package main, which cannot be imported", obj.Name())
+	case v.output != "" && !importable(pkg.Path(), v.output):
+		return fmt.Sprintf("%s is declared in internal package %s, which %s cannot import", obj.Name(), pkg.Path(), v.output)
+	case v.importsOutput(pkg.Path()):
+		return fmt.Sprintf("%s is declared in package %s, which imports the generated package %s, so importing it back would be a cycle", obj.Name(), pkg.Path(), v.output)
+	}
+
+	return ""
+}
+
+// importsOutput reports whether the package at importPath imports the
+// generated package, directly or through the other loaded packages, so the
+// generated package cannot import it without an import cycle.
+func (v *VertexParser) importsOutput(importPath string) bool {
+	if v.output == "" {
+		return false
+	}
+
+	loaded := make(map[string]*packages.Package)
+	for _, pkg := range v.pkgs {
+		loaded[pkg.PkgPath] = pkg
+	}
+
+	seen := make(map[string]bool)
+	var imports func(string) bool
+	imports = func(importPath string) bool {
+		pkg, ok := loaded[importPath]
+		if !ok || seen[importPath] {
+			return false
+		}
+		seen[importPath] = true
+
+		for _, imported := range importPaths(pkg) {
+			if imported == v.output || imports(imported) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return imports(importPath)
+}
+
+// importPaths returns the import paths of the packages pkg imports, read
+// from its syntax as well, since the go command leaves the imports forming a
+// cycle out of pkg.Imports. That happens once the generated package imports
+// pkg back.
+func importPaths(pkg *packages.Package) []string {
+	var paths []string
+	for imported := range pkg.Imports {
+		paths = append(paths, imported)
+	}
+	for _, node := range pkg.Syntax {
+		for _, spec := range node.Imports {
+			if imported, err := strconv.Unquote(spec.Path.Value); err == nil {
+				paths = append(paths, imported)
+			}
+		}
+	}
+
+	return paths
+}
+
+// importable reports whether the package at importPath may be imported by
+// the package at from. Packages below an internal directory may only be
+// imported from the tree rooted at the parent of that directory.
+func importable(importPath, from string) bool {
+	var parent string
+	switch {
+	case strings.HasSuffix(importPath, "/internal"):
+		parent = strings.TrimSuffix(importPath, "/internal")
+	case strings.Contains(importPath, "/internal/"):
+		parent = importPath[:strings.LastIndex(importPath, "/internal/")]
+	case importPath == "internal" || strings.HasPrefix(importPath, "internal/"):
+		return false
+	default:
+		return true
+	}
+
+	return from == parent || strings.HasPrefix(from, parent+"/")
+}
@@ -0,0 +1,200 @@
+package parser
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestCheckReferable(t *testing.T) {
+	users := loadTestPackage(t, token.NewFileSet(), "example.com/app/users", testFile{"users.go", `package users
+
+type UserID int
+
+type userID int
+
+type Status interface{ Active() bool }
+
+type ID = userID
+
+type Service struct{}
+
+type service struct{}
+
+// @server path=/users method=POST
+func Save(id UserID, status Status, ids map[string][]*userID) (ID, error) { return 0, nil }
+
+// @server path=/service method=POST
+func (s *service) Get() {}
+
+// @server path=/exported method=POST
+func (s *Service) Get(fn func(userID)) {}
+
+// @server path=/list method=POST
+func list(ids []UserID) {}
+
+// @server path=/count method=GET
+func (s *Service) count() int { return 0 }
+`})
+	require.Empty(t, users.Errors)
+
+	vp := NewVertexParser(nil, []*packages.Package{users}, config.Config{})
+	vp.output = "example.com/app/vertex"
+	vp.resolvePackages()
+	functions := vp.parseFunctions(users, users.Syntax[0])
+
+	var got []string
+	for _, d := range vp.Diagnostics() {
+		got = append(got, d.Message)
+	}
+
+	assert.Len(t, functions, 5)
+	assert.Equal(t, []string{
+		"Save: parameter ids of type map[string][]*users.userID cannot be used by the generated code: userID is unexported",
+		"Get: receiver of type *users.service cannot be used by the generated code: service is unexported",
+		"Get: parameter fn of type func(users.userID) cannot be used by the generated code: userID is unexported",
+		"list: unexported functions cannot be served, since the generated package cannot call them",
+		"count: unexported functions cannot be served, since the generated package cannot call them",
+	}, got)
+}
+
+func TestCheckReferableMain(t *testing.T) {
+	pkg := loadTestPackage(t, token.NewFileSet(), "example.com/app", testFile{"main.go", `package main
+
+type Config struct{}
+
+// @server path=/health method=GET
+func Health() {}
+
+func main() {}
+`})
+	require.Empty(t, pkg.Errors)
+
+	vp := NewVertexParser(nil, []*packages.Package{pkg}, config.Config{})
+	vp.resolvePackages()
+	vp.parseFunctions(pkg, pkg.Syntax[0])
+
+	var got []string
+	for _, d := range vp.Diagnostics() {
+		got = append(got, d.Message)
+	}
+	assert.Equal(t, []string{"Health: functions in package main cannot be served, since the generated package cannot import it"}, got)
+}
+
+func TestCheckReferableInternal(t *testing.T) {
+	pkg := loadTestPackage(t, token.NewFileSet(), "example.com/app/api/internal/billing", testFile{"billing.go", `package billing
+
+type Invoice struct{ Total int }
+
+// @server path=/invoices method=POST
+func Pay(invoice Invoice) {}
+`})
+	require.Empty(t, pkg.Errors)
+
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{
+			name:     "Output outside the internal tree",
+			output:   "example.com/app/vertex",
+			expected: []string{"Pay: internal package example.com/app/api/internal/billing cannot be imported by the generated package example.com/app/vertex"},
+		},
+		{
+			name:   "Output inside the internal tree",
+			output: "example.com/app/api/vertex",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vp := NewVertexParser(nil, []*packages.Package{pkg}, config.Config{})
+			vp.output = tt.output
+			vp.resolvePackages()
+			vp.parseFunctions(pkg, pkg.Syntax[0])
+
+			var got []string
+			for _, d := range vp.Diagnostics() {
+				got = append(got, d.Message)
+			}
+			assert.Equal(t, tt.expected, got)
+
+			invoice := pkg.Types.Scope().Lookup("Invoice").Type()
+			if tt.expected != nil {
+				assert.Equal(t, "Invoice is declared in internal package example.com/app/api/internal/billing, which example.com/app/vertex cannot import", vp.unreferable(invoice, make(map[gotypes.Type]bool)))
+			} else {
+				assert.Empty(t, vp.unreferable(invoice, make(map[gotypes.Type]bool)))
+			}
+		})
+	}
+}
+
+func TestCheckReferableCycle(t *testing.T) {
+	users := loadTestPackage(t, token.NewFileSet(), "example.com/app/users", testFile{"users.go", `package users
+
+// @server path=/users method=GET
+func List() {}
+`})
+	require.Empty(t, users.Errors)
+	users.Imports = map[string]*packages.Package{"example.com/app/mail": {PkgPath: "example.com/app/mail"}}
+
+	// The go command leaves an import forming a cycle out of Imports, so mail
+	// imports the generated package in its syntax only.
+	mailSrc, err := parser.ParseFile(token.NewFileSet(), "mail.go", `package mail; import _ "example.com/app/vertex"`, parser.ImportsOnly)
+	require.NoError(t, err)
+	mail := &packages.Package{PkgPath: "example.com/app/mail", Types: gotypes.NewPackage("example.com/app/mail", "mail"), Syntax: []*ast.File{mailSrc}, Imports: map[string]*packages.Package{
+		"example.com/app/users": users,
+	}}
+	orders := &packages.Package{PkgPath: "example.com/app/orders", Types: gotypes.NewPackage("example.com/app/orders", "orders"), Imports: map[string]*packages.Package{
+		"example.com/app/users": users,
+	}}
+
+	vp := NewVertexParser(nil, []*packages.Package{users, mail, orders}, config.Config{})
+	vp.output = "example.com/app/vertex"
+	vp.resolvePackages()
+	vp.parseFunctions(users, users.Syntax[0])
+
+	var got []string
+	for _, d := range vp.Diagnostics() {
+		got = append(got, d.Message)
+	}
+	assert.Equal(t, []string{"List: package example.com/app/users imports the generated package example.com/app/vertex, which would import it back; call the generated code from a package that is not served"}, got)
+
+	assert.True(t, vp.importsOutput("example.com/app/mail"))
+	assert.False(t, vp.importsOutput("example.com/app/vertex/internal"))
+
+	order := gotypes.NewTypeName(token.NoPos, gotypes.NewPackage("example.com/app/orders", "orders"), "Order", nil)
+	gotypes.NewNamed(order, gotypes.NewStruct(nil, nil), nil)
+	assert.Equal(t, "Order is declared in package example.com/app/orders, which imports the generated package example.com/app/vertex, so importing it back would be a cycle", vp.unreferableName(order))
+}
+
+func TestImportable(t *testing.T) {
+	tests := []struct {
+		importPath string
+		from       string
+		expected   bool
+	}{
+		{importPath: "example.com/app/users", from: "example.com/app/vertex", expected: true},
+		{importPath: "example.com/app/internal/billing", from: "example.com/app/vertex", expected: true},
+		{importPath: "example.com/app/internal", from: "example.com/app/gen/api", expected: true},
+		{importPath: "example.com/app/api/internal/billing", from: "example.com/app/api/gen", expected: true},
+		{importPath: "example.com/app/api/internal/billing", from: "example.com/app/vertex", expected: false},
+		{importPath: "example.com/app/api/internal/billing", from: "example.com/app/apis", expected: false},
+		{importPath: "example.com/app/internal/x/internal/y", from: "example.com/app/internal/z", expected: false},
+		{importPath: "internal/poll", from: "example.com/app/vertex", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.importPath+" from "+tt.from, func(t *testing.T) {
+			assert.Equal(t, tt.expected, importable(tt.importPath, tt.from))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	gotypes "go/types"
+	"slices"
+	"strings"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/codegen/utils"
+)
+
+// resolveServices returns the structs whose methods are served, together
+// with the constructor each instance is created by.
+func (v *VertexParser) resolveServices(functions []types.FunctionInfo) []types.Service {
+	seen := make(map[string]bool)
+	var services []types.Service
+	for _, fn := range functions {
+		key := fn.PackageAlias + "." + fn.StructName
+		if !fn.IsMethod || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		service := types.Service{PackageAlias: fn.PackageAlias, StructName: fn.StructName}
+		if scope := v.scopeOf(fn.PackageAlias); scope != nil {
+			named, _ := scope.Lookup(fn.StructName).(*gotypes.TypeName)
+			if constructor, ok := scope.Lookup("New" + fn.StructName).(*gotypes.Func); ok && named != nil {
+				service.Constructor = v.parseConstructor(constructor, named)
+			}
+			if named != nil {
+				structType, ok := named.Type().Underlying().(*gotypes.Struct)
+				service.HasFields = ok && structType.NumFields() > 0
+				if service.HasFields && scope.Lookup("New"+fn.StructName) == nil {
+					v.diagnostics.Warnf(v.position(named.Pos()), "%s has fields but no New%s constructor, so the server fails to start unless it is given one with WithService or WithProvider", fn.StructName, fn.StructName)
+				}
+			}
+		}
+
+		services = append(services, service)
+	}
+
+	slices.SortFunc(services, func(a, b types.Service) int {
+		return strings.Compare(a.PackageAlias+"."+a.StructName, b.PackageAlias+"."+b.StructName)
+	})
+
+	return services
+}
+
+// scopeOf returns the package scope of the parsed package with the given
+// alias.
+func (v *VertexParser) scopeOf(alias string) *gotypes.Scope {
+	for _, pkg := range v.pkgs {
+		if v.packageOf(pkg.Types).Alias == alias {
+			return pkg.Types.Scope()
+		}
+	}
+
+	return nil
+}
+
+// parseConstructor checks that a constructor returns the struct or a pointer
+// to it, optionally followed by an error, and records the parameters the
+// generated server passes it. A leading context.Context is passed by the
+// server and a trailing variadic parameter is left empty; the others are
+// looked up by type among the dependencies the server is given, so no two of
+// them may share a type.
+func (v *VertexParser) parseConstructor(fn *gotypes.Func, named *gotypes.TypeName) *types.Constructor {
+	sig := fn.Signature()
+	returnsValue, ok := constructedType(sig.Results(), named.Type())
+	if !ok {
+		v.diagnostics.Errorf(v.position(fn.Pos()), "%s: constructors must return %s or *%s, optionally followed by an error", fn.Name(), named.Name(), named.Name())
+		return nil
+	}
+
+	params := v.parseParams(sig)
+	if sig.Variadic() {
+		params = params[:len(params)-1]
+	}
+
+	paramTypes := v.paramTypes(sig)[:len(params)]
+	for i, t := range paramTypes {
+		v.checkReferable(v.position(fn.Pos()), fn.Name(), "parameter "+params[i].Name, t)
+
+		for j, other := range paramTypes[:i] {
+			if gotypes.Identical(t, other) {
+				v.diagnostics.Errorf(v.position(fn.Pos()), "%s: parameters %s and %s are both of type %s, so WithDependency cannot tell them apart; create the %s with WithProvider instead", fn.Name(), params[j].Name, params[i].Name, params[i].Type, named.Name())
+			}
+		}
+	}
+
+	return &types.Constructor{
+		Name:         fn.Name(),
+		Params:       params,
+		HasContext:   v.parseContext(sig),
+		ReturnsValue: returnsValue,
+		ReturnsError: sig.Results().Len() == 2,
+	}
+}
+
+// constructedType reports whether constructor results are the struct or a
+// pointer to it, optionally followed by an error, and which of the two.
+func constructedType(results *gotypes.Tuple, structType gotypes.Type) (returnsValue bool, ok bool) {
+	switch results.Len() {
+	case 1:
+	case 2:
+		if !utils.IsErrorType(results.At(1).Type()) {
+			return false, false
+		}
+	default:
+		return false, false
+	}
+
+	t := results.At(0).Type()
+	if gotypes.Identical(t, structType) {
+		return true, true
+	}
+
+	return false, gotypes.Identical(t, gotypes.NewPointer(structType))
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestResolveServices(t *testing.T) {
+	pkg := loadTestPackage(t, token.NewFileSet(), "example.com/app/users", testFile{"users.go", `package users
+		import (
+			"context"
+			dbsql "database/sql"
+			"net/http"
+		)
+
+		type Option func(*UserService)
+
+		type UserService struct{ db *dbsql.DB }
+		func NewUserService(ctx context.Context, db *dbsql.DB, client *http.Client, opts ...Option) (*UserService, error) { return nil, nil }
+
+		type Counter struct{ start int }
+		func NewCounter() Counter { return Counter{} }
+
+		type Plain struct{}
+
+		type Count int
+
+		type Stateful struct{ n int }
+
+		type Config struct{}
+		type Configured struct{}
+		func NewConfigured(config Config) *Configured { return &Configured{} }
+
+		type Broken struct{}
+		func NewBroken() (*Broken, bool) { return nil, false }
+
+		type Mirror struct{ primary, replica *dbsql.DB }
+		func NewMirror(primary, replica *dbsql.DB) *Mirror { return &Mirror{primary, replica} }
+	`})
+	require.Empty(t, pkg.Errors)
+
+	functions := []types.FunctionInfo{
+		{Name: "Get", IsMethod: true, StructName: "UserService", PackageAlias: "users"},
+		{Name: "List", IsMethod: true, StructName: "UserService", PackageAlias: "users"},
+		{Name: "Next", IsMethod: true, StructName: "Counter", PackageAlias: "users"},
+		{Name: "Value", IsMethod: true, StructName: "Plain", PackageAlias: "users"},
+		{Name: "Next", IsMethod: true, StructName: "Count", PackageAlias: "users"},
+		{Name: "Count", IsMethod: true, StructName: "Stateful", PackageAlias: "users"},
+		{Name: "Name", IsMethod: true, StructName: "Configured", PackageAlias: "users"},
+		{Name: "Fix", IsMethod: true, StructName: "Broken", PackageAlias: "users"},
+		{Name: "Sync", IsMethod: true, StructName: "Mirror", PackageAlias: "users"},
+		{Name: "Health", PackageAlias: "users"},
+	}
+
+	vp := NewVertexParser(nil, []*packages.Package{pkg}, config.Config{})
+	vp.resolvePackages()
+	services := vp.resolveServices(functions)
+
+	assert.Equal(t, []types.Service{
+		{PackageAlias: "users", StructName: "Broken"},
+		{
+			PackageAlias: "users",
+			StructName:   "Configured",
+			Constructor: &types.Constructor{
+				Name:   "NewConfigured",
+				Params: []types.ParamInfo{{Name: "config", Type: "users.Config"}},
+			},
+		},
+		{PackageAlias: "users", StructName: "Count"},
+		{
+			PackageAlias: "users",
+			StructName:   "Counter",
+			Constructor:  &types.Constructor{Name: "NewCounter", ReturnsValue: true},
+			HasFields:    true,
+		},
+		{
+			PackageAlias: "users",
+			StructName:   "Mirror",
+			Constructor: &types.Constructor{
+				Name:   "NewMirror",
+				Params: []types.ParamInfo{{Name: "primary", Type: "*sql.DB"}, {Name: "replica", Type: "*sql.DB"}},
+			},
+			HasFields: true,
+		},
+		{PackageAlias: "users", StructName: "Plain"},
+		{PackageAlias: "users", StructName: "Stateful", HasFields: true},
+		{
+			PackageAlias: "users",
+			StructName:   "UserService",
+			Constructor: &types.Constructor{
+				Name:         "NewUserService",
+				Params:       []types.ParamInfo{{Name: "db", Type: "*sql.DB"}, {Name: "client", Type: "*http.Client"}},
+				HasContext:   true,
+				ReturnsError: true,
+			},
+			HasFields: true,
+		},
+	}, services)
+
+	var got []string
+	for _, d := range vp.Diagnostics() {
+		got = append(got, d.String())
+	}
+	assert.Equal(t, []string{
+		"warning: Stateful has fields but no NewStateful constructor, so the server fails to start unless it is given one with WithService or WithProvider",
+		"error: NewBroken: constructors must return Broken or *Broken, optionally followed by an error",
+		"error: NewMirror: parameters primary and replica are both of type *sql.DB, so WithDependency cannot tell them apart; create the Mirror with WithProvider instead",
+	}, got)
+
+	assert.Equal(t, map[string]types.Package{
+		"database/sql":          {Name: "sql", Alias: "sql", ImportPath: "database/sql"},
+		"example.com/app/users": {Name: "users", Alias: "users", ImportPath: "example.com/app/users"},
+		"net/http":              {Name: "http", Alias: "http", ImportPath: "net/http"},
+	}, vp.referenced)
+}
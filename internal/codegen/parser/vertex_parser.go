@@ -3,26 +3,176 @@ package parser
 import (
 	"fmt"
 	"go/ast"
-	"regexp"
+	"go/token"
+	gotypes "go/types"
+	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/jackparsonss/vertex/internal/codegen/diagnostics"
 	"github.com/jackparsonss/vertex/internal/codegen/gomod"
 	"github.com/jackparsonss/vertex/internal/codegen/types"
 	"github.com/jackparsonss/vertex/internal/codegen/utils"
 	"github.com/jackparsonss/vertex/internal/config"
 	"github.com/jackparsonss/vertex/internal/constants"
+	"golang.org/x/tools/go/packages"
 )
 
+// reservedIdents are declared by the generated package itself, either at
+// package level or on the generated Client, so client functions cannot be
+// generated under these names, and neither parameters nor imports may take
+// them. The request types, named after their function with a Request suffix,
+// are matched by generatedIdent.
+var reservedIdents = map[string]bool{
+	"AddrEnv":                true,
+	"BaseURL":                true,
+	"BaseURLEnv":             true,
+	"Client":                 true,
+	"CodeConflict":           true,
+	"CodeInternal":           true,
+	"CodeInvalidArgument":    true,
+	"CodeNotFound":           true,
+	"CodePermissionDenied":   true,
+	"CodeUnauthenticated":    true,
+	"CodeUnavailable":        true,
+	"CodeUnknown":            true,
+	"DefaultAddr":            true,
+	"DefaultBaseURL":         true,
+	"DefaultClient":          true,
+	"DefaultShutdownTimeout": true,
+	"Error":                  true,
+	"ErrorHandler":           true,
+	"HTTPClient":             true,
+	"Handler":                true,
+	"Interceptor":            true,
+	"Interceptors":           true,
+	"Middleware":             true,
+	"NewClient":              true,
+	"NewServer":              true,
+	"OnShutdown":             true,
+	"OnStart":                true,
+	"Option":                 true,
+	"RegisterMiddleware":     true,
+	"RegisterRoutes":         true,
+	"RequestFunc":            true,
+	"Server":                 true,
+	"ServerOption":           true,
+	"ServiceURLs":            true,
+	"StartServer":            true,
+	"TimeoutHeader":          true,
+	"Use":                    true,
+	"WithAddr":               true,
+	"WithDependency":         true,
+	"WithErrorHandler":       true,
+	"WithHTTPClient":         true,
+	"WithInterceptors":       true,
+	"WithMux":                true,
+	"WithProvider":           true,
+	"WithService":            true,
+	"WithServiceURL":         true,
+	"WithShutdownTimeout":    true,
+	"WithSignals":            true,
+	"WithTLSConfig":          true,
+	"checkMiddleware":        true,
+	"closeService":           true,
+	"codeForStatus":          true,
+	"decodePathValue":        true,
+	"decodeQuery":            true,
+	"decodeRequest":          true,
+	"dependency":             true,
+	"do":                     true,
+	"durationType":           true,
+	"encodeQuery":            true,
+	"errorResponse":          true,
+	"expandPath":             true,
+	"formatQueryValue":       true,
+	"globalMiddleware":       true,
+	"handle":                 true,
+	"handlers":               true,
+	"invalidRequest":         true,
+	"isTextType":             true,
+	"middlewareMu":           true,
+	"namedMiddleware":        true,
+	"parseQueryValue":        true,
+	"readError":              true,
+	"registerRoutes":         true,
+	"reportError":            true,
+	"requestContext":         true,
+	"resolveService":         true,
+	"routes":                 true,
+	"serverConfig":           true,
+	"textMarshalerType":      true,
+	"textUnmarshalerType":    true,
+	"toError":                true,
+	"url":                    true,
+	"writeError":             true,
+}
+
+// generatedIdent reports whether the generated package declares name at
+// package level.
+func generatedIdent(name string) bool {
+	return reservedIdents[name] || strings.HasSuffix(name, "Request")
+}
+
 type VertexParser struct {
-	nodes  []*ast.File
-	config config.Config
-	gomod  *gomod.GoMod
+	fset        *token.FileSet
+	pkgs        []*packages.Package
+	config      config.Config
+	diagnostics diagnostics.Diagnostics
+
+	// funcs holds the type-checked object of every function declaration,
+	// and instances the signature of the generic ones served instantiated.
+	funcs     map[*ast.FuncDecl]*gotypes.Func
+	instances map[*ast.FuncDecl]*gotypes.Signature
+
+	// packages holds the import of every package seen so far by import path,
+	// and referenced the ones the generated code refers to a type of.
+	packages   map[string]types.Package
+	referenced map[string]types.Package
+	taken      map[string]bool
+
+	// output is the import path of the generated package, which decides the
+	// internal packages it may import.
+	output string
 }
 
-func NewVertexParser(nodes []*ast.File, config config.Config) *VertexParser {
-	return &VertexParser{
-		nodes: nodes, config: config, gomod: gomod.NewGoMod(config.GoModFile),
+// NewVertexParser returns a parser for the @server directives of the given
+// packages, which must have been loaded with their syntax and type
+// information.
+func NewVertexParser(fset *token.FileSet, pkgs []*packages.Package, config config.Config) *VertexParser {
+	v := &VertexParser{
+		fset: fset, pkgs: pkgs, config: config,
+		funcs:      make(map[*ast.FuncDecl]*gotypes.Func),
+		instances:  make(map[*ast.FuncDecl]*gotypes.Signature),
+		packages:   make(map[string]types.Package),
+		referenced: make(map[string]types.Package),
+		taken:      make(map[string]bool),
 	}
+
+	for _, pkg := range pkgs {
+		for _, node := range pkg.Syntax {
+			for _, decl := range node.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+
+				if obj, ok := pkg.TypesInfo.Defs[fn.Name].(*gotypes.Func); ok {
+					v.funcs[fn] = obj
+				}
+			}
+		}
+	}
+
+	return v
+}
+
+// Diagnostics returns the warnings and errors reported while parsing.
+func (v *VertexParser) Diagnostics() diagnostics.Diagnostics {
+	return v.diagnostics
 }
 
 func (v *VertexParser) Parse() (types.Vertex, error) {
@@ -31,186 +181,599 @@ func (v *VertexParser) Parse() (types.Vertex, error) {
 		return types.Vertex{}, err
 	}
 
-	err = v.gomod.AddReplace()
-	if err != nil {
-		return types.Vertex{}, err
-	}
+	v.output = v.outputPath(goModPackage)
+	v.checkPackages()
+	v.resolvePackages()
 
-	err = v.gomod.Tidy()
-	if err != nil {
-		return types.Vertex{}, err
+	functions := []types.FunctionInfo{}
+	for _, pkg := range v.pkgs {
+		for _, node := range pkg.Syntax {
+			v.checkComments(node)
+			functions = append(functions, v.parseFunctions(pkg, node)...)
+		}
 	}
 
-	functions := []types.FunctionInfo{}
-	for _, node := range v.nodes {
-		structs := v.parseStructDelcarations(node)
-		functions = append(functions, v.parseFunctions(node, structs)...)
+	v.checkRoutes(functions)
+	v.assignIdents(functions)
+	services := v.resolveServices(functions)
+	v.assignParamIdents(functions)
+	v.diagnostics.Sort()
+	if err := v.diagnostics.Err(); err != nil {
+		return types.Vertex{}, err
 	}
 
 	return types.Vertex{
 		GoModPackage: goModPackage,
 		Functions:    functions,
+		Packages:     usedPackages(functions, v.referenced),
+		Services:     services,
 	}, nil
 }
 
-func (v *VertexParser) parseStructDelcarations(node *ast.File) types.DeclarationMap {
-	structs := make(types.DeclarationMap)
-	ast.Inspect(node, func(n ast.Node) bool {
-		typeSpec, ok := n.(*ast.TypeSpec)
-		if !ok {
-			return true
+// checkPackages reports the errors packages were loaded with as warnings.
+// Their types are still resolved as far as possible, and the errors may well
+// be caused by generated code that does not exist yet.
+func (v *VertexParser) checkPackages() {
+	for _, pkg := range v.pkgs {
+		for _, err := range pkg.Errors {
+			v.diagnostics.Warnf(errorPosition(err.Pos), "%s", err.Msg)
 		}
+	}
+}
 
-		if _, ok := typeSpec.Type.(*ast.StructType); ok {
-			structs[typeSpec.Name.Name] = node.Name.Name
-		}
-		return true
-	})
+// errorPosition parses the "file:line:col" position of a package error.
+func errorPosition(pos string) token.Position {
+	rest, col, ok := cutLast(pos)
+	if !ok {
+		return token.Position{}
+	}
+
+	filename, line, ok := cutLast(rest)
+	if !ok {
+		return token.Position{Filename: rest, Line: col}
+	}
 
-	return structs
+	return token.Position{Filename: filename, Line: line, Column: col}
 }
 
-func (v *VertexParser) parseReceiver(fn *ast.FuncDecl, packageName types.DeclarationMap) (string, string, bool) {
-	isMethod := fn.Recv != nil && len(fn.Recv.List) > 0
-	if !isMethod {
-		return "", "", false
+func cutLast(s string) (string, int, bool) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return s, 0, false
 	}
 
-	var receiverTypeName, structName string
-	receiverExpr := fn.Recv.List[0].Type
-	receiverTypeName = utils.GetTypeString(receiverExpr, packageName)
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return s, 0, false
+	}
 
-	if starExpr, ok := fn.Recv.List[0].Type.(*ast.StarExpr); ok {
-		if ident, ok := starExpr.X.(*ast.Ident); ok {
-			structName = ident.Name
-		}
-	} else if ident, ok := fn.Recv.List[0].Type.(*ast.Ident); ok {
-		structName = ident.Name
+	return s[:i], n, true
+}
+
+// signature returns the type-checked signature of a function declaration,
+// instantiated with the type arguments it is served with if it is generic.
+func (v *VertexParser) signature(fn *ast.FuncDecl) *gotypes.Signature {
+	if sig, ok := v.instances[fn]; ok {
+		return sig
+	}
+
+	if obj, ok := v.funcs[fn]; ok {
+		return obj.Signature()
 	}
 
-	return receiverTypeName, structName, isMethod
+	return gotypes.NewSignatureType(nil, nil, nil, nil, nil, false)
 }
 
-func (v *VertexParser) parseFunction(fn *ast.FuncDecl, structsMap types.DeclarationMap, packageName string) *types.FunctionInfo {
-	path, method := v.parseComment(fn)
-	if path == "" && method == "" {
+// parseReceiver returns the receiver type of a method as the generated code
+// spells it, and the name of the struct the method is declared on.
+func (v *VertexParser) parseReceiver(sig *gotypes.Signature) (string, string, bool) {
+	recv := sig.Recv()
+	if recv == nil {
+		return "", "", false
+	}
+
+	var structName string
+	t := recv.Type()
+	if ptr, ok := t.(*gotypes.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := gotypes.Unalias(t).(*gotypes.Named); ok {
+		structName = named.Obj().Name()
+	}
+
+	return v.typeString(recv.Type()), structName, true
+}
+
+func (v *VertexParser) parseFunction(fn *ast.FuncDecl, packageName string) *types.FunctionInfo {
+	annotation := v.parseComment(fn)
+	if annotation.Path == "" {
 		return nil
 	}
+	path, method := annotation.Path, annotation.Method
+
+	typeArgs, ok := v.instantiate(fn, annotation.TypeArgs)
+	if !ok {
+		return nil
+	}
+
+	sig := v.signature(fn)
+	receiverTypeName, structName, isMethod := v.parseReceiver(sig)
+	params := v.parseParams(sig)
+	hasContext := v.parseContext(sig)
+	returns, returnsError := v.parseReturns(sig)
+
+	v.bindPathParams(fn, path, params)
+	if !utils.MethodHasBody(method) {
+		v.validateQueryParams(fn, method, params)
+	}
 
-	receiverTypeName, structName, isMethod := v.parseReceiver(fn, structsMap)
-	params := v.parseParams(fn, structsMap)
-	returnType, isSlice := v.parseReturnType(fn, structsMap)
+	if method == "HEAD" && len(returns) > 0 {
+		v.diagnostics.Errorf(v.position(fn.Pos()), "%s: HEAD endpoints cannot return values other than an error", fn.Name.Name)
+	}
 
 	return &types.FunctionInfo{
+		Pos:              v.position(fn.Pos()),
 		Name:             fn.Name.Name,
 		Path:             path,
 		Method:           method,
 		Params:           params,
-		ReturnType:       returnType,
-		IsSlice:          isSlice,
+		HasContext:       hasContext,
+		Returns:          returns,
+		ReturnsError:     returnsError,
 		ReceiverTypeName: receiverTypeName,
 		StructName:       structName,
 		IsMethod:         isMethod,
 		PackageName:      packageName,
+		Middleware:       annotation.Middleware,
+		TypeArgs:         typeArgs,
 	}
 }
 
-func (v *VertexParser) parseParams(fn *ast.FuncDecl, structsMap types.DeclarationMap) []types.ParamInfo {
+// parseParams returns the parameters of a function, leaving out a leading
+// context.Context. Unnamed parameters are named after their position.
+func (v *VertexParser) parseParams(sig *gotypes.Signature) []types.ParamInfo {
 	var params []types.ParamInfo
-	if fn.Type.Params == nil {
-		return params
+	for i := range sig.Params().Len() {
+		param := sig.Params().At(i)
+		if i == 0 && v.parseContext(sig) {
+			continue
+		}
+
+		name := param.Name()
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("param%d", i)
+		}
+
+		paramType := v.typeString(param.Type())
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			paramType = "..." + v.typeString(param.Type().(*gotypes.Slice).Elem())
+		}
+
+		params = append(params, types.ParamInfo{Name: name, Type: paramType})
+	}
+
+	return params
+}
+
+// validateQueryParams records an error for every parameter of a GET, DELETE
+// or HEAD endpoint that is sent in the query string but whose type cannot be encoded in one.
+func (v *VertexParser) validateQueryParams(fn *ast.FuncDecl, method string, params []types.ParamInfo) {
+	for i, t := range v.paramTypes(v.signature(fn)) {
+		if params[i].InPath || utils.IsQueryType(t) {
+			continue
+		}
+
+		v.diagnostics.Errorf(v.position(fn.Pos()), "%s: parameter %s of type %s cannot be sent in a %s query string", fn.Name.Name, params[i].Name, params[i].Type, method)
 	}
+}
+
+// bindPathParams marks the parameters named by wildcards in the path pattern
+// as bound from the URL path, and records an error for wildcards that do not
+// name a parameter that can be parsed from a path segment.
+func (v *VertexParser) bindPathParams(fn *ast.FuncDecl, path string, params []types.ParamInfo) {
+	paramTypes := v.paramTypes(v.signature(fn))
+	seen := make(map[string]bool)
+	for _, name := range utils.PathParams(path) {
+		if seen[name] {
+			v.diagnostics.Errorf(v.position(fn.Pos()), "%s: path %s uses wildcard {%s} more than once", fn.Name.Name, path, name)
+			continue
+		}
+		seen[name] = true
+
+		i := slices.IndexFunc(params, func(p types.ParamInfo) bool { return p.Name == name })
+		if i < 0 {
+			v.diagnostics.Errorf(v.position(fn.Pos()), "%s: path wildcard {%s} does not match any parameter", fn.Name.Name, name)
+			continue
+		}
 
-	for i, param := range fn.Type.Params.List {
-		paramType := utils.GetTypeString(param.Type, structsMap)
-		if len(param.Names) == 0 {
-			params = append(params, types.ParamInfo{Name: fmt.Sprintf("param%d", i), Type: paramType})
+		if !utils.IsPathType(paramTypes[i]) {
+			v.diagnostics.Errorf(v.position(fn.Pos()), "%s: parameter %s of type %s cannot be bound to path wildcard {%s}", fn.Name.Name, name, params[i].Type, name)
 			continue
 		}
 
-		for _, name := range param.Names {
-			params = append(params, types.ParamInfo{Name: name.Name, Type: paramType})
+		params[i].InPath = true
+	}
+}
+
+// paramTypes returns the types of the function's parameters, in the same
+// order as parseParams.
+func (v *VertexParser) paramTypes(sig *gotypes.Signature) []gotypes.Type {
+	var paramTypes []gotypes.Type
+	for i := range sig.Params().Len() {
+		if i == 0 && v.parseContext(sig) {
+			continue
 		}
+
+		paramTypes = append(paramTypes, sig.Params().At(i).Type())
 	}
 
-	return params
+	return paramTypes
+}
+
+// parseContext reports whether the function takes a context.Context as its
+// first parameter. The context is supplied by the transport rather than
+// being encoded in the request, so it is not listed in the function's params.
+func (v *VertexParser) parseContext(sig *gotypes.Signature) bool {
+	return sig.Params().Len() > 0 && utils.IsContextType(sig.Params().At(0).Type())
 }
 
-func (v *VertexParser) parseComment(fn *ast.FuncDecl) (string, string) {
+func (v *VertexParser) parseComment(fn *ast.FuncDecl) serverDirective {
 	if fn.Doc == nil {
-		return "", ""
+		return serverDirective{}
 	}
 
 	var path, method string
+	var middleware, typeArgs []string
 	for _, comment := range fn.Doc.List {
-		text := comment.Text
-		if !strings.Contains(text, constants.SERVER_DIRECTIVE) {
+		d, ok := parseDirective(comment.Text)
+		if !ok {
 			continue
 		}
 
-		pathDirective := strings.TrimSuffix(constants.PATH_DIRECTIVE, "=")
-		methodDirective := strings.TrimSuffix(constants.METHOD_DIRECTIVE, "=")
+		path, method, middleware, typeArgs = "", v.defaultMethod(), nil, nil
+		for _, stray := range d.Strays {
+			v.diagnostics.Warnf(v.position(comment.Pos()+token.Pos(stray.Offset)), "%s: ignoring %q in @server directive, expected key=value", fn.Name.Name, stray.Text)
+		}
 
-		pathPattern := regexp.MustCompile(pathDirective + `\s*=\s*(\S+)`)
-		pathMatches := pathPattern.FindStringSubmatch(text)
-		if len(pathMatches) > 1 {
-			path = pathMatches[1]
+		valid := true
+		for _, field := range d.Fields {
+			pos := v.position(comment.Pos() + token.Pos(field.Offset))
+			switch field.Key + "=" {
+			case constants.PATH_DIRECTIVE:
+				if field.Value == "" {
+					v.diagnostics.Errorf(pos, "%s: missing value for path in @server directive", fn.Name.Name)
+				}
+				path = field.Value
+			case constants.METHOD_DIRECTIVE:
+				if field.Value == "" {
+					v.diagnostics.Warnf(pos, "%s: missing value for method in @server directive, defaulting to %s", fn.Name.Name, v.defaultMethod())
+					continue
+				}
+
+				method = strings.ToUpper(field.Value)
+				if !utils.AllowedMethods[method] {
+					v.diagnostics.Errorf(pos, "%s: unsupported method %s", fn.Name.Name, field.Value)
+					valid = false
+				}
+			case constants.MIDDLEWARE_DIRECTIVE:
+				for _, name := range strings.Split(field.Value, ",") {
+					if name == "" {
+						v.diagnostics.Errorf(pos, "%s: empty middleware name in @server directive", fn.Name.Name)
+						valid = false
+						continue
+					}
+
+					if slices.Contains(middleware, name) {
+						v.diagnostics.Warnf(pos, "%s: middleware %s is listed more than once", fn.Name.Name, name)
+						continue
+					}
+
+					middleware = append(middleware, name)
+				}
+			case constants.INSTANTIATE_DIRECTIVE:
+				typeArgs = splitTypeArgs(field.Value)
+				if slices.Contains(typeArgs, "") {
+					v.diagnostics.Errorf(pos, "%s: empty type argument in @server directive", fn.Name.Name)
+					valid = false
+				}
+			default:
+				valid = false
+				if suggestion, ok := closest(field.Key, directiveKeys); ok {
+					v.diagnostics.Errorf(pos, "%s: unknown key %q in @server directive, did you mean %q?", fn.Name.Name, field.Key, suggestion)
+				} else {
+					v.diagnostics.Errorf(pos, "%s: unknown key %q in @server directive", fn.Name.Name, field.Key)
+				}
+			}
 		}
 
 		if path == "" {
-			return "", ""
+			if !slices.ContainsFunc(d.Fields, func(f directiveField) bool { return f.Key+"=" == constants.PATH_DIRECTIVE }) {
+				v.diagnostics.Errorf(v.position(comment.Pos()+token.Pos(d.Offset)), "%s: @server directive is missing required key path", fn.Name.Name)
+			}
+			return serverDirective{}
 		}
 
-		methodPattern := regexp.MustCompile(methodDirective + `\s*=\s*(\S+)`)
-		methodMatches := methodPattern.FindStringSubmatch(text)
-		if len(methodMatches) > 1 {
-			method = methodMatches[1]
+		if !valid {
+			return serverDirective{}
 		}
+	}
+
+	return serverDirective{Path: path, Method: method, Middleware: middleware, TypeArgs: typeArgs}
+}
+
+// defaultMethod returns the method of directives that do not declare one.
+func (v *VertexParser) defaultMethod() string {
+	if v.config.DefaultMethod != "" {
+		return v.config.DefaultMethod
+	}
+
+	return constants.DEFAULT_METHOD
+}
+
+// checkComments reports @server directives that are not attached to a
+// function declaration, and annotations that look like a misspelled @server.
+func (v *VertexParser) checkComments(node *ast.File) {
+	docs := make(map[*ast.CommentGroup]bool)
+	for _, decl := range node.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Doc != nil {
+			docs[fn.Doc] = true
+		}
+	}
 
-		if method == "" {
-			return "", ""
+	for _, group := range node.Comments {
+		for _, comment := range group.List {
+			if d, ok := parseDirective(comment.Text); ok && !docs[group] {
+				v.diagnostics.Warnf(v.position(comment.Pos()+token.Pos(d.Offset)), "@server directive is not attached to a function declaration and is ignored")
+			}
+
+			for _, match := range annotationPattern.FindAllStringSubmatchIndex(comment.Text, -1) {
+				word := comment.Text[match[4]:match[5]]
+				if "@"+word == constants.SERVER_DIRECTIVE {
+					continue
+				}
+
+				if _, ok := closest(word, []string{strings.TrimPrefix(constants.SERVER_DIRECTIVE, "@")}); ok {
+					v.diagnostics.Warnf(v.position(comment.Pos()+token.Pos(match[2])), "unknown directive @%s, did you mean %s?", word, constants.SERVER_DIRECTIVE)
+				}
+			}
 		}
 	}
+}
 
-	return path, method
+// checkRoutes reports routes net/http.ServeMux would refuse to register:
+// invalid patterns and patterns conflicting with an earlier route, such as the
+// same method and path or wildcards that only differ in name.
+func (v *VertexParser) checkRoutes(functions []types.FunctionInfo) {
+	mux := http.NewServeMux()
+	var registered []types.FunctionInfo
+	for _, fn := range functions {
+		pattern := utils.Route(fn.Method, fn.Path)
+		err := registerRoute(mux, pattern)
+		if err == nil {
+			registered = append(registered, fn)
+			continue
+		}
+
+		first, ok := conflictingRoute(registered, pattern)
+		switch {
+		case !ok:
+			v.diagnostics.Errorf(fn.Pos, "%s: invalid route %s: %v", fn.Name, pattern, err)
+		case utils.Route(first.Method, first.Path) == pattern:
+			v.diagnostics.Errorf(fn.Pos, "%s: duplicate route %s, already declared by %s at %s", fn.Name, pattern, first.Name, first.Pos)
+		default:
+			v.diagnostics.Errorf(fn.Pos, "%s: route %s conflicts with %s declared by %s at %s", fn.Name, pattern, utils.Route(first.Method, first.Path), first.Name, first.Pos)
+		}
+	}
 }
 
-func (v *VertexParser) parseFunctions(node *ast.File, structsMap types.DeclarationMap) []types.FunctionInfo {
-	packageName := node.Name.Name
+// conflictingRoute returns the first registered function whose route cannot
+// be registered alongside pattern.
+func conflictingRoute(registered []types.FunctionInfo, pattern string) (types.FunctionInfo, bool) {
+	for _, fn := range registered {
+		mux := http.NewServeMux()
+		if err := registerRoute(mux, utils.Route(fn.Method, fn.Path)); err != nil {
+			continue
+		}
 
-	var functions []types.FunctionInfo
-	ast.Inspect(node, func(n ast.Node) bool {
-		fn, ok := n.(*ast.FuncDecl)
-		if !ok {
-			return true
+		if err := registerRoute(mux, pattern); err != nil {
+			return fn, true
+		}
+	}
+
+	return types.FunctionInfo{}, false
+}
+
+// registerRoute registers pattern on mux, turning the panic ServeMux raises
+// for invalid or conflicting patterns into an error.
+func registerRoute(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	mux.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+	return nil
+}
+
+// assignIdents picks the identifier each function's handler, client function
+// and request type are generated under. It is the function name unless
+// another annotated function shares it, in which case methods are prefixed
+// with their struct name and anything still ambiguous with its package alias.
+func (v *VertexParser) assignIdents(functions []types.FunctionInfo) {
+	counts := make(map[string]int)
+	for _, fn := range functions {
+		counts[fn.Name]++
+	}
+
+	for i := range functions {
+		fn := &functions[i]
+		fn.Ident = fn.Name
+		if counts[fn.Name] > 1 && fn.IsMethod {
+			fn.Ident = fn.StructName + fn.Name
+		}
+	}
+
+	clear(counts)
+	for _, fn := range functions {
+		counts[fn.Ident]++
+	}
+
+	for i := range functions {
+		fn := &functions[i]
+		if counts[fn.Ident] > 1 {
+			fn.Ident = exportedName(fn.PackageAlias) + fn.Ident
+		}
+	}
+
+	seen := make(map[string]types.FunctionInfo)
+	for _, fn := range functions {
+		if reservedIdents[fn.Ident] {
+			v.diagnostics.Errorf(fn.Pos, "%s: generated name %s is already declared by the generated package; rename the function", fn.Name, fn.Ident)
+			continue
 		}
 
-		if fn.Doc == nil {
-			return true
+		if first, ok := seen[fn.Ident]; ok {
+			v.diagnostics.Errorf(fn.Pos, "%s: generated name %s collides with %s at %s; rename one of them", fn.Name, fn.Ident, first.Name, first.Pos)
+			continue
+		}
+
+		seen[fn.Ident] = fn
+	}
+}
+
+// localPrefix starts the names of the variables the generated code declares
+// next to the parameters, so that they cannot clash with them.
+const localPrefix = "vx"
+
+// assignParamIdents names the variables holding the parameters in the
+// generated code and their fields in the request struct. A parameter keeps
+// its name unless it would hide a package or predeclared identifier the
+// generated code uses, a name of the generated package or its context
+// variable, or starts with localPrefix; underscores are appended to it then.
+// Fields are exported names, unique within the function, that differ from
+// the JSON key only in that. Run it once every package has its alias.
+func (v *VertexParser) assignParamIdents(functions []types.FunctionInfo) {
+	for i := range functions {
+		fn := &functions[i]
+
+		used := make(map[string]bool)
+		for _, param := range fn.Params {
+			used[param.Name] = true
+		}
+
+		fields := make(map[string]bool)
+		for j := range fn.Params {
+			param := &fn.Params[j]
+
+			param.Field = fieldName(param.Name)
+			for fields[param.Field] {
+				param.Field += "_"
+			}
+			fields[param.Field] = true
+
+			param.Ident = param.Name
+			if !v.hidesName(param.Name) && !(fn.HasContext && param.Name == "ctx") {
+				continue
+			}
+
+			for param.Ident += "_"; used[param.Ident]; param.Ident += "_" {
+			}
+			used[param.Ident] = true
+		}
+	}
+}
+
+// fieldName exports a parameter name so it can be used as a struct field.
+func fieldName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	field := string(unicode.ToUpper(r)) + name[size:]
+	if !token.IsExported(field) {
+		return "Param" + name
+	}
+
+	return field
+}
+
+// hidesName reports whether a variable called name would hide an identifier
+// the generated code refers to, or may clash with one of its locals.
+func (v *VertexParser) hidesName(name string) bool {
+	_, imported := reservedAliases[name]
+
+	return imported || v.taken[name] || generatedIdent(name) || gotypes.Universe.Lookup(name) != nil || strings.HasPrefix(name, localPrefix)
+}
+
+// exportedName upper-cases the first letter of a package name so it can
+// prefix an exported identifier.
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (v *VertexParser) position(pos token.Pos) token.Position {
+	if v.fset == nil {
+		return token.Position{}
+	}
+
+	return v.fset.Position(pos)
+}
+
+func (v *VertexParser) parseFunctions(pkg *packages.Package, node *ast.File) []types.FunctionInfo {
+	p := v.packageOf(pkg.Types)
+
+	var functions []types.FunctionInfo
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Doc == nil {
+			continue
 		}
 
-		f := v.parseFunction(fn, structsMap, packageName)
+		f := v.parseFunction(fn, p.Name)
 		if f == nil {
-			return true
+			continue
 		}
 
-		functions = append(functions, *f)
+		f.PackageAlias = p.Alias
+		f.ImportPath = p.ImportPath
+
+		switch {
+		case p.Name == "main":
+			v.diagnostics.Errorf(f.Pos, "%s: functions in package main cannot be served, since the generated package cannot import it", f.Name)
+		case !fn.Name.IsExported():
+			v.diagnostics.Errorf(f.Pos, "%s: unexported functions cannot be served, since the generated package cannot call them", f.Name)
+		case v.output != "" && !importable(p.ImportPath, v.output):
+			v.diagnostics.Errorf(f.Pos, "%s: internal package %s cannot be imported by the generated package %s", f.Name, p.ImportPath, v.output)
+		case v.importsOutput(p.ImportPath):
+			v.diagnostics.Errorf(f.Pos, "%s: package %s imports the generated package %s, which would import it back; call the generated code from a package that is not served", f.Name, p.ImportPath, v.output)
+		default:
+			v.checkSignature(f.Pos, f.Name, v.signature(fn), f.Params)
+		}
 
-		return true
-	})
+		functions = append(functions, *f)
+	}
 
 	return functions
 }
 
-func (v *VertexParser) parseReturnType(fn *ast.FuncDecl, structsMap types.DeclarationMap) (string, bool) {
-	if fn.Type.Results == nil || len(fn.Type.Results.List) == 0 {
-		return "", false
+// parseReturns returns the results of a function and whether the last of
+// them is an error, which is left out of the returned values.
+func (v *VertexParser) parseReturns(sig *gotypes.Signature) ([]types.ReturnInfo, bool) {
+	var returns []types.ReturnInfo
+	results := sig.Results()
+	for i := range results.Len() {
+		returns = append(returns, types.ReturnInfo{Type: v.typeString(results.At(i).Type())})
+	}
+
+	if len(returns) == 0 || !utils.IsErrorType(results.At(results.Len()-1).Type()) {
+		return returns, false
 	}
 
-	isSlice := false
-	if _, ok := fn.Type.Results.List[0].Type.(*ast.ArrayType); ok {
-		isSlice = true
+	if len(returns) == 1 {
+		return nil, true
 	}
 
-	return utils.GetTypeString(fn.Type.Results.List[0].Type, structsMap), isSlice
+	return returns[:len(returns)-1], true
 }
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"fmt"
 	"go/ast"
+	"go/importer"
 	"go/parser"
 	"go/token"
+	gotypes "go/types"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -13,6 +16,8 @@ import (
 	"github.com/jackparsonss/vertex/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/imports"
 )
 
 func TestParse(t *testing.T) {
@@ -24,15 +29,16 @@ func TestParse(t *testing.T) {
 	err = os.WriteFile(filePath, []byte("module vertex_test"), 0644)
 	assert.NoError(t, err)
 
-	file1 := parseGoFile(t, `package vertex_pkg_two
+	fset := token.NewFileSet()
+	pkg1 := loadTestPackage(t, fset, "vertex_test/vertex_pkg_two", testFile{"two.go", `package vertex_pkg_two
 		// @server path=/data method=POST
 		func SaveData(data string) error { return nil }
-	`)
-	file2 := parseGoFile(t, `package vertex_pkg_one
+	`})
+	pkg2 := loadTestPackage(t, fset, "vertex_test/vertex_pkg_one", testFile{"one.go", `package vertex_pkg_one
 		// @server path=/items method=GET
-		func GetData() (string, error) { return nil }
-	`)
-	vp := NewVertexParser([]*ast.File{file1, file2}, config.Config{InputDir: tempDir, GoModFile: filePath})
+		func GetData() (string, error) { return "", nil }
+	`})
+	vp := NewVertexParser(nil, []*packages.Package{pkg1, pkg2}, config.Config{InputRoots: []string{tempDir}, GoModFile: filePath})
 
 	v, err := vp.Parse()
 	assert.NoError(t, err)
@@ -41,26 +47,31 @@ func TestParse(t *testing.T) {
 	assert.Len(t, v.Functions, 2)
 	assert.Equal(t, types.FunctionInfo{
 		Name:             "SaveData",
+		Ident:            "SaveData",
 		Path:             "/data",
 		Method:           "POST",
-		ReturnType:       "error",
-		IsSlice:          false,
+		ReturnsError:     true,
 		IsMethod:         false,
 		ReceiverTypeName: "",
 		StructName:       "",
 		PackageName:      "vertex_pkg_two",
-		Params:           []types.ParamInfo{{Name: "data", Type: "string"}},
+		PackageAlias:     "vertex_pkg_two",
+		ImportPath:       "vertex_test/vertex_pkg_two",
+		Params:           []types.ParamInfo{{Name: "data", Type: "string", Ident: "data", Field: "Data"}},
 	}, v.Functions[0])
 	assert.Equal(t, types.FunctionInfo{
 		Name:             "GetData",
+		Ident:            "GetData",
 		Path:             "/items",
 		Method:           "GET",
-		ReturnType:       "string",
-		IsSlice:          false,
+		Returns:          []types.ReturnInfo{{Type: "string"}},
+		ReturnsError:     true,
 		IsMethod:         false,
 		ReceiverTypeName: "",
 		StructName:       "",
 		PackageName:      "vertex_pkg_one",
+		PackageAlias:     "vertex_pkg_one",
+		ImportPath:       "vertex_test/vertex_pkg_one",
 		Params:           nil,
 	}, v.Functions[1])
 
@@ -69,22 +80,215 @@ func TestParse(t *testing.T) {
 		t.Fatalf("Failed to read file after execution: %v", err)
 	}
 
-	replacePattern := regexp.MustCompile(`replace\s+vertex\s*=>\s*\./vertex`)
-	hasReplace := replacePattern.MatchString(string(content))
+	// The generated package is imported by its path within the module, so
+	// go.mod needs no replace directive for it.
+	assert.NotRegexp(t, regexp.MustCompile(`replace\s+vertex`), string(content))
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	src := `package users
+
+// @sever path=/typo method=GET
+func Typo() {}
+
+// @server path=/users mehtod=GET
+func ListUsers() {}
+
+// @server method=GET
+func MissingPath() {}
+
+// @server path=/users method=FETCH
+func Fetch() {}
+
+// @server path=/users method=GET
+type Users struct{}
+
+// @server path=/users/{id} method=GET
+func GetUser(id int) {}
+
+// @server path=/users/{id} method=get
+func GetUserAgain(id int) {}
+
+// @server path=/users/{id} method=POST
+func SaveUser(id int) {}
+
+// Send mails the report to ops@server.example.com.
+func Send() {}
+
+// Deploy runs on @serverless infrastructure, see ops@sever.example.com.
+func Deploy() {}
+`
+	fset := token.NewFileSet()
+	pkg := loadTestPackage(t, fset, "example.com/app/users", testFile{"users.go", src})
+	node := pkg.Syntax[0]
+
+	vp := NewVertexParser(fset, []*packages.Package{pkg}, config.Config{})
+	vp.checkComments(node)
+	functions := vp.parseFunctions(pkg, node)
+	vp.checkRoutes(functions)
+
+	var got []string
+	for _, d := range vp.Diagnostics() {
+		got = append(got, d.String())
+	}
+
+	assert.Equal(t, []string{
+		"users.go:3:4: warning: unknown directive @sever, did you mean @server?",
+		"users.go:15:4: warning: @server directive is not attached to a function declaration and is ignored",
+		"users.go:6:24: error: ListUsers: unknown key \"mehtod\" in @server directive, did you mean \"method\"?",
+		"users.go:9:4: error: MissingPath: @server directive is missing required key path",
+		"users.go:12:24: error: Fetch: unsupported method FETCH",
+		"users.go:22:1: error: GetUserAgain: duplicate route GET /users/{id}, already declared by GetUser at users.go:19:1",
+	}, got)
+	assert.Len(t, functions, 3)
+	assert.Equal(t, 4, vp.Diagnostics().ErrorCount())
+}
+
+func TestCheckRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   [][2]string
+		expected []string
+	}{
+		{
+			name:   "distinct routes",
+			routes: [][2]string{{"GET", "/users/{id}"}, {"POST", "/users/{id}"}, {"ANY", "/users"}, {"GET", "/users"}},
+		},
+		{
+			name:     "same method and path",
+			routes:   [][2]string{{"GET", "/users"}, {"GET", "/users"}},
+			expected: []string{"F1: duplicate route GET /users, already declared by F0 at -"},
+		},
+		{
+			name:     "wildcards differing in name",
+			routes:   [][2]string{{"GET", "/users/{id}"}, {"GET", "/users/{name}"}},
+			expected: []string{"F1: route GET /users/{name} conflicts with GET /users/{id} declared by F0 at -"},
+		},
+		{
+			name:     "overlapping wildcards",
+			routes:   [][2]string{{"ANY", "/{org}/users"}, {"ANY", "/teams/{team}"}},
+			expected: []string{"F1: route /teams/{team} conflicts with /{org}/users declared by F0 at -"},
+		},
+		{
+			name:     "invalid pattern",
+			routes:   [][2]string{{"GET", "users"}},
+			expected: []string{"F0: invalid route GET users: parsing \"GET users\": at offset 4: host/path missing /"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var functions []types.FunctionInfo
+			for i, route := range tt.routes {
+				functions = append(functions, types.FunctionInfo{Name: fmt.Sprintf("F%d", i), Method: route[0], Path: route[1]})
+			}
+
+			vp := &VertexParser{}
+			vp.checkRoutes(functions)
+
+			var got []string
+			for _, d := range vp.Diagnostics() {
+				got = append(got, d.Message)
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestAssignIdents(t *testing.T) {
+	functions := []types.FunctionInfo{
+		{Name: "Get", PackageAlias: "users", IsMethod: true, StructName: "UserService"},
+		{Name: "Get", PackageAlias: "orders", IsMethod: true, StructName: "OrderService"},
+		{Name: "List", PackageAlias: "users"},
+		{Name: "List", PackageAlias: "orders"},
+		{Name: "Save", PackageAlias: "users", IsMethod: true, StructName: "Service"},
+		{Name: "Save", PackageAlias: "orders", IsMethod: true, StructName: "Service"},
+		{Name: "Delete", PackageAlias: "users"},
+	}
+
+	vp := &VertexParser{}
+	vp.assignIdents(functions)
+
+	var idents []string
+	for _, fn := range functions {
+		idents = append(idents, fn.Ident)
+	}
+	assert.Equal(t, []string{"UserServiceGet", "OrderServiceGet", "UsersList", "OrdersList", "UsersServiceSave", "OrdersServiceSave", "Delete"}, idents)
+	assert.Empty(t, vp.Diagnostics())
+}
+
+func TestAssignParamIdents(t *testing.T) {
+	functions := []types.FunctionInfo{
+		{Name: "RW", Params: []types.ParamInfo{{Name: "r"}, {Name: "w"}, {Name: "request"}, {Name: "query"}, {Name: "path"}, {Name: "err"}}},
+		{Name: "Fetch", Params: []types.ParamInfo{{Name: "url"}, {Name: "url_"}, {Name: "json"}, {Name: "users"}}},
+		{Name: "Search", HasContext: true, Params: []types.ParamInfo{{Name: "ctx"}, {Name: "string"}, {Name: "DefaultClient"}, {Name: "vxErr"}}},
+		{Name: "Find", Params: []types.ParamInfo{{Name: "ctx"}, {Name: "id"}, {Name: "findRequest"}, {Name: "writeError"}}},
+	}
 
-	if !hasReplace {
-		t.Error("Replace directive was not found in the file after execution")
+	vp := &VertexParser{taken: map[string]bool{"users": true}}
+	vp.assignParamIdents(functions)
+
+	var idents [][]string
+	for _, fn := range functions {
+		var params []string
+		for _, param := range fn.Params {
+			params = append(params, param.Ident)
+		}
+		idents = append(idents, params)
 	}
+	assert.Equal(t, [][]string{
+		{"r", "w", "request", "query", "path", "err"},
+		{"url__", "url_", "json_", "users_"},
+		{"ctx_", "string_", "DefaultClient_", "vxErr_"},
+		{"ctx", "id", "findRequest_", "writeError_"},
+	}, idents)
+}
+
+func TestAssignParamFields(t *testing.T) {
+	functions := []types.FunctionInfo{
+		{Name: "Echo", Params: []types.ParamInfo{{Name: "_id"}, {Name: "s"}, {Name: "a"}, {Name: "A"}, {Name: "A_"}, {Name: "名前"}}},
+	}
+
+	vp := &VertexParser{}
+	vp.assignParamIdents(functions)
+
+	var fields []string
+	for _, param := range functions[0].Params {
+		fields = append(fields, param.Field)
+	}
+	assert.Equal(t, []string{"Param_id", "S", "A", "A_", "A__", "Param名前"}, fields)
+}
+
+func TestAssignIdentsCollisions(t *testing.T) {
+	functions := []types.FunctionInfo{
+		{Name: "Get", PackageAlias: "users"},
+		{Name: "List", PackageAlias: "users"},
+		{Name: "List", PackageAlias: "users"},
+		{Name: "NewClient", PackageAlias: "users"},
+	}
+
+	vp := &VertexParser{}
+	vp.assignIdents(functions)
+
+	var got []string
+	for _, d := range vp.Diagnostics() {
+		got = append(got, d.Message)
+	}
+	assert.Equal(t, []string{
+		"List: generated name UsersList collides with List at -; rename one of them",
+		"NewClient: generated name NewClient is already declared by the generated package; rename the function",
+	}, got)
 }
 
 func TestParseComment(t *testing.T) {
 	vp := &VertexParser{}
 
 	tests := []struct {
-		name           string
-		commentCode    string
-		expectedPath   string
-		expectedMethod string
+		name               string
+		commentCode        string
+		expectedPath       string
+		expectedMethod     string
+		expectedMiddleware []string
 	}{
 		{
 			name:           "Both path and method in same comment",
@@ -158,36 +362,65 @@ func TestParseComment(t *testing.T) {
 			name:           "Method with lowercase",
 			commentCode:    "// @server path=/api/data method=get\nfunc GetDataLowercase() {}",
 			expectedPath:   "/api/data",
-			expectedMethod: "get",
+			expectedMethod: "GET",
+		},
+		{
+			name:           "Method defaults to POST",
+			commentCode:    "// @server path=/api/data\nfunc SaveData() {}",
+			expectedPath:   "/api/data",
+			expectedMethod: "POST",
+		},
+		{
+			name:           "Empty method defaults to POST",
+			commentCode:    "// @server path=/api/data method=\nfunc SaveData() {}",
+			expectedPath:   "/api/data",
+			expectedMethod: "POST",
+		},
+		{
+			name:               "Middleware",
+			commentCode:        "// @server path=/api/data method=GET middleware=auth,ratelimit\nfunc GetData() {}",
+			expectedPath:       "/api/data",
+			expectedMethod:     "GET",
+			expectedMiddleware: []string{"auth", "ratelimit"},
+		},
+		{
+			name:               "Repeated middleware",
+			commentCode:        "// @server path=/api/data middleware=auth,auth\nfunc SaveData() {}",
+			expectedPath:       "/api/data",
+			expectedMethod:     "POST",
+			expectedMiddleware: []string{"auth"},
+		},
+		{
+			name:           "Empty middleware name",
+			commentCode:    "// @server path=/api/data middleware=auth,\nfunc SaveData() {}",
+			expectedPath:   "",
+			expectedMethod: "",
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			fn := parseFunctionCode(t, tc.commentCode)
+			_, fn := parseFunctionCode(t, tc.commentCode)
 
-			path, method := vp.parseComment(fn)
+			annotation := vp.parseComment(fn)
 
-			assert.Equal(t, tc.expectedPath, path, "Path should match expected value")
-			assert.Equal(t, tc.expectedMethod, method, "Method should match expected value")
+			assert.Equal(t, tc.expectedPath, annotation.Path, "Path should match expected value")
+			assert.Equal(t, tc.expectedMethod, annotation.Method, "Method should match expected value")
+			assert.Equal(t, tc.expectedMiddleware, annotation.Middleware, "Middleware should match expected value")
 		})
 	}
 }
 
 func TestParseParams(t *testing.T) {
-	vp := VertexParser{}
-
 	tests := []struct {
 		name           string
 		functionCode   string
 		expectedParams []types.ParamInfo
-		structsMap     types.DeclarationMap
 	}{
 		{
 			name:           "No parameters",
 			functionCode:   "func NoParams() {}",
 			expectedParams: []types.ParamInfo{},
-			structsMap:     types.DeclarationMap{},
 		},
 		{
 			name:         "Single named parameter",
@@ -195,7 +428,6 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "name", Type: "string"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Multiple parameters of different types",
@@ -205,7 +437,6 @@ func TestParseParams(t *testing.T) {
 				{Name: "name", Type: "string"},
 				{Name: "active", Type: "bool"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Multiple parameters of same type",
@@ -214,7 +445,6 @@ func TestParseParams(t *testing.T) {
 				{Name: "first", Type: "string"},
 				{Name: "second", Type: "string"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Unnamed parameter",
@@ -222,7 +452,14 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "param0", Type: "string"},
 			},
-			structsMap: types.DeclarationMap{},
+		},
+		{
+			name:         "Blank parameter",
+			functionCode: "func BlankParam(_ string, id int) {}",
+			expectedParams: []types.ParamInfo{
+				{Name: "param0", Type: "string"},
+				{Name: "id", Type: "int"},
+			},
 		},
 		{
 			name:         "Array parameter",
@@ -230,7 +467,6 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "items", Type: "[]string"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Map parameter",
@@ -238,15 +474,13 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "data", Type: "map[string]interface{}"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Pointer parameter",
-			functionCode: "func PointerParam(user *User) {}",
+			functionCode: "type User struct{}\nfunc PointerParam(user *User) {}",
 			expectedParams: []types.ParamInfo{
-				{Name: "user", Type: "*User"},
+				{Name: "user", Type: "*testpkg.User"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Channel parameter",
@@ -254,7 +488,6 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "ch", Type: "chan string"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Function parameter",
@@ -262,7 +495,6 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "callback", Type: "func(int) bool"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Interface parameter",
@@ -270,17 +502,15 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "data", Type: "interface{}"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Mixed complex parameters",
-			functionCode: "func ComplexParams(id int, users []*User, options map[string]interface{}) {}",
+			functionCode: "type User struct{}\nfunc ComplexParams(id int, users []*User, options map[string]interface{}) {}",
 			expectedParams: []types.ParamInfo{
 				{Name: "id", Type: "int"},
-				{Name: "users", Type: "[]*User"},
+				{Name: "users", Type: "[]*testpkg.User"},
 				{Name: "options", Type: "map[string]interface{}"},
 			},
-			structsMap: types.DeclarationMap{},
 		},
 		{
 			name:         "Variadic parameter",
@@ -288,7 +518,28 @@ func TestParseParams(t *testing.T) {
 			expectedParams: []types.ParamInfo{
 				{Name: "messages", Type: "...string"},
 			},
-			structsMap: types.DeclarationMap{},
+		},
+		{
+			name:         "Leading context parameter",
+			functionCode: "func WithContext(ctx context.Context, id int) {}",
+			expectedParams: []types.ParamInfo{
+				{Name: "id", Type: "int"},
+			},
+		},
+		{
+			name:         "Unnamed leading context parameter",
+			functionCode: "func WithContext(context.Context, int) {}",
+			expectedParams: []types.ParamInfo{
+				{Name: "param1", Type: "int"},
+			},
+		},
+		{
+			name:         "Context parameter not in first position",
+			functionCode: "func WithContext(id int, ctx context.Context) {}",
+			expectedParams: []types.ParamInfo{
+				{Name: "id", Type: "int"},
+				{Name: "ctx", Type: "context.Context"},
+			},
 		},
 		{
 			name:         "Package qualified types",
@@ -297,17 +548,36 @@ func TestParseParams(t *testing.T) {
 				{Name: "t", Type: "time.Time"},
 				{Name: "b", Type: "bytes.Buffer"},
 			},
-			structsMap: types.DeclarationMap{
-				"Time":   "time",
-				"Buffer": "bytes",
+		},
+		{
+			name:         "Renamed import",
+			functionCode: "import tm \"time\"\nfunc Renamed(d tm.Duration) {}",
+			expectedParams: []types.ParamInfo{
+				{Name: "d", Type: "time.Duration"},
+			},
+		},
+		{
+			name:         "Named non-struct types",
+			functionCode: "type UserID int\ntype Status string\nfunc NamedTypes(id UserID, statuses []Status) {}",
+			expectedParams: []types.ParamInfo{
+				{Name: "id", Type: "testpkg.UserID"},
+				{Name: "statuses", Type: "[]testpkg.Status"},
+			},
+		},
+		{
+			name:         "Type alias",
+			functionCode: "type Stamp = time.Time\ntype IDs = []int\nfunc Aliases(at Stamp, ids IDs) {}",
+			expectedParams: []types.ParamInfo{
+				{Name: "at", Type: "testpkg.Stamp"},
+				{Name: "ids", Type: "testpkg.IDs"},
 			},
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			fn := parseFunctionCode(t, tc.functionCode)
-			params := vp.parseParams(fn, tc.structsMap)
+			vp, fn := parseFunctionCode(t, tc.functionCode)
+			params := vp.parseParams(vp.signature(fn))
 
 			assert.Equal(t, len(tc.expectedParams), len(params),
 				"Number of parameters should match")
@@ -324,13 +594,12 @@ func TestParseParams(t *testing.T) {
 	}
 }
 
-func TestParseReturnType(t *testing.T) {
+func TestParseReturns(t *testing.T) {
 	tests := []struct {
 		name            string
 		functionCode    string
-		structsMap      types.DeclarationMap
-		expectedType    string
-		expectedIsSlice bool
+		expectedReturns []types.ReturnInfo
+		expectedError   bool
 	}{
 		{
 			name: "No return type",
@@ -339,9 +608,7 @@ func TestParseReturnType(t *testing.T) {
 					// Function with no return type
 				}
 			`,
-			structsMap:      types.DeclarationMap{},
-			expectedType:    "",
-			expectedIsSlice: false,
+			expectedReturns: nil,
 		},
 		{
 			name: "Basic return type",
@@ -350,9 +617,7 @@ func TestParseReturnType(t *testing.T) {
 					return "hello"
 				}
 			`,
-			structsMap:      types.DeclarationMap{},
-			expectedType:    "string",
-			expectedIsSlice: false,
+			expectedReturns: []types.ReturnInfo{{Type: "string"}},
 		},
 		{
 			name: "Pointer return type",
@@ -362,9 +627,7 @@ func TestParseReturnType(t *testing.T) {
 					return &x
 				}
 			`,
-			structsMap:      types.DeclarationMap{},
-			expectedType:    "*int",
-			expectedIsSlice: false,
+			expectedReturns: []types.ReturnInfo{{Type: "*int"}},
 		},
 		{
 			name: "Slice return type",
@@ -373,9 +636,7 @@ func TestParseReturnType(t *testing.T) {
 					return []string{"hello", "world"}
 				}
 			`,
-			structsMap:      types.DeclarationMap{},
-			expectedType:    "[]string",
-			expectedIsSlice: true,
+			expectedReturns: []types.ReturnInfo{{Type: "[]string"}},
 		},
 		{
 			name: "Map return type",
@@ -384,131 +645,109 @@ func TestParseReturnType(t *testing.T) {
 					return map[string]int{"one": 1}
 				}
 			`,
-			structsMap:      types.DeclarationMap{},
-			expectedType:    "map[string]int",
-			expectedIsSlice: false,
+			expectedReturns: []types.ReturnInfo{{Type: "map[string]int"}},
 		},
 		{
 			name: "Custom type return",
 			functionCode: `
+				type CustomType struct{}
 				func CustomReturn() CustomType {
 					return CustomType{}
 				}
 			`,
-			structsMap:      types.DeclarationMap{"CustomType": "mypackage"},
-			expectedType:    "mypackage.CustomType",
-			expectedIsSlice: false,
+			expectedReturns: []types.ReturnInfo{{Type: "testpkg.CustomType"}},
 		},
 		{
 			name: "Slice of custom type return",
 			functionCode: `
+				type CustomType struct{}
 				func CustomSliceReturn() []CustomType {
 					return []CustomType{}
 				}
 			`,
-			structsMap:      types.DeclarationMap{"CustomType": "mypackage"},
-			expectedType:    "[]mypackage.CustomType",
-			expectedIsSlice: true,
+			expectedReturns: []types.ReturnInfo{{Type: "[]testpkg.CustomType"}},
 		},
 		{
-			name: "Multiple return values (should take first only)",
+			name: "Value and error return",
 			functionCode: `
 				func MultipleReturn() (string, error) {
 					return "result", nil
 				}
 			`,
-			structsMap:      types.DeclarationMap{},
-			expectedType:    "string",
-			expectedIsSlice: false,
+			expectedReturns: []types.ReturnInfo{{Type: "string"}},
+			expectedError:   true,
 		},
 		{
-			name: "Selector expression return type",
+			name: "Multiple values without error",
 			functionCode: `
-				func SelectorReturn() fmt.Stringer {
-					return nil
+				func Lookup(k string) (string, bool) {
+					return "", false
 				}
 			`,
-			structsMap:      types.DeclarationMap{},
-			expectedType:    "fmt.Stringer",
-			expectedIsSlice: false,
+			expectedReturns: []types.ReturnInfo{{Type: "string"}, {Type: "bool"}},
+			expectedError:   false,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			funcDecl := parseFunctionCode(t, tt.functionCode)
-
-			vp := NewVertexParser([]*ast.File{}, config.Config{})
-
-			returnType, isSlice := vp.parseReturnType(funcDecl, tt.structsMap)
-
-			assert.Equal(t, tt.expectedType, returnType, "Return type should match expected")
-			assert.Equal(t, tt.expectedIsSlice, isSlice, "IsSlice flag should match expected")
-		})
-	}
-}
-
-func TestParseStructDeclarations(t *testing.T) {
-	tests := []struct {
-		name        string
-		code        string
-		expectedMap types.DeclarationMap
-	}{
 		{
-			name:        "No declarations",
-			code:        `package testpkg`,
-			expectedMap: types.DeclarationMap{},
+			name: "Multiple values with error",
+			functionCode: `
+				func Divide(a, b int) (int, int, error) {
+					return 0, 0, nil
+				}
+			`,
+			expectedReturns: []types.ReturnInfo{{Type: "int"}, {Type: "int"}},
+			expectedError:   true,
 		},
 		{
-			name: "Single struct declaration",
-			code: `
-				package testpkg
-				type MyStruct struct {}
+			name: "Grouped named return values",
+			functionCode: `
+				type CustomType struct{}
+				func Bounds() (lo, hi CustomType) {
+					return
+				}
 			`,
-			expectedMap: types.DeclarationMap{"MyStruct": "testpkg"},
+			expectedReturns: []types.ReturnInfo{{Type: "testpkg.CustomType"}, {Type: "testpkg.CustomType"}},
+			expectedError:   false,
 		},
 		{
-			name: "Multiple struct declarations",
-			code: `
-				package testpkg
-				type StructA struct {}
-				type StructB struct {}
+			name: "Error only return",
+			functionCode: `
+				func ErrorReturn() error {
+					return nil
+				}
 			`,
-			expectedMap: types.DeclarationMap{
-				"StructA": "testpkg",
-				"StructB": "testpkg",
-			},
+			expectedReturns: nil,
+			expectedError:   true,
 		},
 		{
-			name: "Mixed type declarations",
-			code: `
-				package testpkg
-				type StructA struct {}
-				type AliasA = int
-				type InterfaceA interface{}
+			name: "Named error return",
+			functionCode: `
+				type CustomType struct{}
+				func NamedErrorReturn() (users []CustomType, err error) {
+					return nil, nil
+				}
 			`,
-			expectedMap: types.DeclarationMap{
-				"StructA": "testpkg",
-			},
+			expectedReturns: []types.ReturnInfo{{Type: "[]testpkg.CustomType"}},
+			expectedError:   true,
 		},
 		{
-			name: "Structs in different package name",
-			code: `
-				package otherpkg
-				type Something struct {}
+			name: "Selector expression return type",
+			functionCode: `
+				func SelectorReturn() fmt.Stringer {
+					return nil
+				}
 			`,
-			expectedMap: types.DeclarationMap{
-				"Something": "otherpkg",
-			},
+			expectedReturns: []types.ReturnInfo{{Type: "fmt.Stringer"}},
 		},
 	}
 
-	parser := &VertexParser{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			node := parseGoFile(t, tt.code)
-			result := parser.parseStructDelcarations(node)
-			assert.Equal(t, tt.expectedMap, result)
+			vp, funcDecl := parseFunctionCode(t, tt.functionCode)
+
+			returns, returnsError := vp.parseReturns(vp.signature(funcDecl))
+
+			assert.Equal(t, tt.expectedReturns, returns, "Return types should match expected")
+			assert.Equal(t, tt.expectedError, returnsError, "ReturnsError flag should match expected")
 		})
 	}
 }
@@ -517,7 +756,6 @@ func TestParseReceiver(t *testing.T) {
 	tests := []struct {
 		name         string
 		code         string
-		typeMap      types.DeclarationMap
 		wantTypeName string
 		wantStruct   string
 		wantMethod   bool
@@ -527,7 +765,6 @@ func TestParseReceiver(t *testing.T) {
 			code: `
 				func DoSomething() {}
 			`,
-			typeMap:      types.DeclarationMap{},
 			wantTypeName: "",
 			wantStruct:   "",
 			wantMethod:   false,
@@ -538,8 +775,7 @@ func TestParseReceiver(t *testing.T) {
 				type MyStruct struct{}
 				func (m MyStruct) DoSomething() {}
 			`,
-			typeMap:      types.DeclarationMap{},
-			wantTypeName: "MyStruct",
+			wantTypeName: "testpkg.MyStruct",
 			wantStruct:   "MyStruct",
 			wantMethod:   true,
 		},
@@ -549,29 +785,16 @@ func TestParseReceiver(t *testing.T) {
 				type MyStruct struct{}
 				func (m *MyStruct) DoSomething() {}
 			`,
-			typeMap:      types.DeclarationMap{},
-			wantTypeName: "*MyStruct",
-			wantStruct:   "MyStruct",
-			wantMethod:   true,
-		},
-		{
-			name: "Method with package-mapped receiver",
-			code: `
-				type MyStruct struct{}
-				func (m *MyStruct) DoSomething() {}
-			`,
-			typeMap:      types.DeclarationMap{"MyStruct": "pkgX"},
-			wantTypeName: "*pkgX.MyStruct",
+			wantTypeName: "*testpkg.MyStruct",
 			wantStruct:   "MyStruct",
 			wantMethod:   true,
 		},
 	}
 
-	parser := &VertexParser{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fn := parseFunctionCode(t, tt.code)
-			typeName, structName, isMethod := parser.parseReceiver(fn, tt.typeMap)
+			vp, fn := parseFunctionCode(t, tt.code)
+			typeName, structName, isMethod := vp.parseReceiver(vp.signature(fn))
 
 			assert.Equal(t, tt.wantTypeName, typeName)
 			assert.Equal(t, tt.wantStruct, structName)
@@ -584,7 +807,6 @@ func TestParseFunction(t *testing.T) {
 	tests := []struct {
 		name         string
 		code         string
-		structsMap   types.DeclarationMap
 		packageName  string
 		expectedFunc *types.FunctionInfo
 	}{
@@ -593,7 +815,6 @@ func TestParseFunction(t *testing.T) {
 			code: `
 				func GetUser() string { return "" }
 			`,
-			structsMap:   types.DeclarationMap{},
 			packageName:  "testpkg",
 			expectedFunc: nil,
 		},
@@ -603,15 +824,13 @@ func TestParseFunction(t *testing.T) {
 				// @server path=/users method=GET
 				func GetUser() string { return "" }
 			`,
-			structsMap:  types.DeclarationMap{},
 			packageName: "testpkg",
 			expectedFunc: &types.FunctionInfo{
 				Name:        "GetUser",
 				Path:        "/users",
 				Method:      "GET",
 				Params:      nil,
-				ReturnType:  "string",
-				IsSlice:     false,
+				Returns:     []types.ReturnInfo{{Type: "string"}},
 				IsMethod:    false,
 				PackageName: "testpkg",
 			},
@@ -623,15 +842,13 @@ func TestParseFunction(t *testing.T) {
 				// @server path=/users method=GET
 				func (u *User) List() []string { return nil }
 			`,
-			structsMap:  types.DeclarationMap{"User": "testpkg"},
 			packageName: "testpkg",
 			expectedFunc: &types.FunctionInfo{
 				Name:             "List",
 				Path:             "/users",
 				Method:           "GET",
 				Params:           nil,
-				ReturnType:       "[]string",
-				IsSlice:          true,
+				Returns:          []types.ReturnInfo{{Type: "[]string"}},
 				IsMethod:         true,
 				ReceiverTypeName: "*testpkg.User",
 				StructName:       "User",
@@ -641,26 +858,44 @@ func TestParseFunction(t *testing.T) {
 		{
 			name: "Function with parameters and custom types",
 			code: `
+				type Meta struct{}
 				// @server path=/items method=POST
-				func CreateUser(name string, age int, meta pkg.Meta) bool { return true }
+				func CreateUser(name string, age int, meta Meta) bool { return true }
 			`,
-			structsMap:  types.DeclarationMap{"Meta": "pkg"},
 			packageName: "testpkg",
 			expectedFunc: &types.FunctionInfo{
-				Name:       "CreateUser",
-				Path:       "/items",
-				Method:     "POST",
-				IsMethod:   false,
-				IsSlice:    false,
-				ReturnType: "bool",
+				Name:     "CreateUser",
+				Path:     "/items",
+				Method:   "POST",
+				IsMethod: false,
+				Returns:  []types.ReturnInfo{{Type: "bool"}},
 				Params: []types.ParamInfo{
 					{Name: "name", Type: "string"},
 					{Name: "age", Type: "int"},
-					{Name: "meta", Type: "pkg.Meta"},
+					{Name: "meta", Type: "testpkg.Meta"},
 				},
 				PackageName: "testpkg",
 			},
 		},
+		{
+			name: "Function with leading context",
+			code: `
+				// @server path=/items method=GET
+				func GetItem(ctx context.Context, id int) string { return "" }
+			`,
+			packageName: "testpkg",
+			expectedFunc: &types.FunctionInfo{
+				Name:       "GetItem",
+				Path:       "/items",
+				Method:     "GET",
+				HasContext: true,
+				Params: []types.ParamInfo{
+					{Name: "id", Type: "int"},
+				},
+				Returns:     []types.ReturnInfo{{Type: "string"}},
+				PackageName: "testpkg",
+			},
+		},
 		{
 			name: "Method with value receiver and no return",
 			code: `
@@ -668,7 +903,6 @@ func TestParseFunction(t *testing.T) {
 				// @server path=/items method=POST
 				func (c Controller) Clear() {}
 			`,
-			structsMap:  types.DeclarationMap{"Controller": "testpkg"},
 			packageName: "testpkg",
 			expectedFunc: &types.FunctionInfo{
 				Name:             "Clear",
@@ -678,38 +912,236 @@ func TestParseFunction(t *testing.T) {
 				ReceiverTypeName: "testpkg.Controller",
 				StructName:       "Controller",
 				Params:           nil,
-				ReturnType:       "",
-				IsSlice:          false,
 				PackageName:      "testpkg",
 			},
 		},
 	}
 
-	parser := &VertexParser{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fn := parseFunctionCode(t, tt.code)
-			result := parser.parseFunction(fn, tt.structsMap, tt.packageName)
+			vp, fn := parseFunctionCode(t, tt.code)
+			result := vp.parseFunction(fn, tt.packageName)
 
 			assert.Equal(t, tt.expectedFunc, result)
 		})
 	}
 }
 
+func TestValidateQueryParams(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		expectedErrs []string
+	}{
+		{
+			name: "Query encodable parameters",
+			code: `
+				// @server path=/users method=GET
+				func ListUsers(ctx context.Context, active bool, since time.Time, ids []int, tags ...string) {}
+			`,
+			expectedErrs: nil,
+		},
+		{
+			name: "Unsupported parameters",
+			code: `
+				// @server path=/users method=GET
+				func ListUsers(filter map[string]string, fn func(), value any) {}
+			`,
+			expectedErrs: []string{
+				"ListUsers: parameter filter of type map[string]string cannot be sent in a GET query string",
+				"ListUsers: parameter fn of type func() cannot be sent in a GET query string",
+				"ListUsers: parameter value of type any cannot be sent in a GET query string",
+			},
+		},
+		{
+			name: "DELETE parameters are sent in the query string",
+			code: `
+				// @server path=/users method=DELETE
+				func DeleteUsers(filter map[string]string) {}
+			`,
+			expectedErrs: []string{
+				"DeleteUsers: parameter filter of type map[string]string cannot be sent in a DELETE query string",
+			},
+		},
+		{
+			name: "Body parameters are not validated",
+			code: `
+				// @server path=/users method=POST
+				func SaveUsers(filter map[string]string) {}
+			`,
+			expectedErrs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vp, fn := parseFunctionCode(t, tt.code)
+			vp.parseFunction(fn, "testpkg")
+
+			var errs []string
+			for _, d := range vp.diagnostics {
+				errs = append(errs, d.Message)
+			}
+
+			assert.Equal(t, tt.expectedErrs, errs)
+		})
+	}
+}
+
+func TestParseFunctionMethodValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		expectNil    bool
+		expectedErrs []string
+	}{
+		{
+			name: "Supported method",
+			code: `
+				// @server path=/items/{id} method=delete
+				func DeleteItem(id int) error { return nil }
+			`,
+		},
+		{
+			name: "Method-agnostic endpoint",
+			code: `
+				// @server path=/items method=ANY
+				func Items(filter map[string]string) {}
+			`,
+		},
+		{
+			name: "Unsupported method",
+			code: `
+				// @server path=/items method=FETCH
+				func FetchItems() {}
+			`,
+			expectNil:    true,
+			expectedErrs: []string{"FetchItems: unsupported method FETCH"},
+		},
+		{
+			name: "HEAD endpoint returning a value",
+			code: `
+				// @server path=/items method=HEAD
+				func HasItems() (bool, error) { return false, nil }
+			`,
+			expectedErrs: []string{"HasItems: HEAD endpoints cannot return values other than an error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vp, decl := parseFunctionCode(t, tt.code)
+			fn := vp.parseFunction(decl, "testpkg")
+
+			var errs []string
+			for _, d := range vp.diagnostics {
+				errs = append(errs, d.Message)
+			}
+
+			assert.Equal(t, tt.expectNil, fn == nil)
+			assert.Equal(t, tt.expectedErrs, errs)
+		})
+	}
+}
+
+func TestBindPathParams(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           string
+		expectedParams []types.ParamInfo
+		expectedErrs   []string
+	}{
+		{
+			name: "Wildcards bind parameters by name",
+			code: `
+				// @server path=/users/{id}/posts/{slug} method=GET
+				func GetPost(ctx context.Context, slug string, id int, verbose bool) {}
+			`,
+			expectedParams: []types.ParamInfo{
+				{Name: "slug", Type: "string", InPath: true},
+				{Name: "id", Type: "int", InPath: true},
+				{Name: "verbose", Type: "bool"},
+			},
+		},
+		{
+			name: "Remaining segments wildcard",
+			code: `
+				// @server path=/files/{path...} method=GET
+				func GetFile(path string) {}
+			`,
+			expectedParams: []types.ParamInfo{
+				{Name: "path", Type: "string", InPath: true},
+			},
+		},
+		{
+			name: "Wildcard without parameter",
+			code: `
+				// @server path=/users/{id} method=POST
+				func SaveUser(name string) {}
+			`,
+			expectedParams: []types.ParamInfo{
+				{Name: "name", Type: "string"},
+			},
+			expectedErrs: []string{
+				"SaveUser: path wildcard {id} does not match any parameter",
+			},
+		},
+		{
+			name: "Wildcard with incompatible parameter",
+			code: `
+				// @server path=/users/{ids} method=POST
+				func SaveUsers(ids []int) {}
+			`,
+			expectedParams: []types.ParamInfo{
+				{Name: "ids", Type: "[]int"},
+			},
+			expectedErrs: []string{
+				"SaveUsers: parameter ids of type []int cannot be bound to path wildcard {ids}",
+			},
+		},
+		{
+			name: "Repeated wildcard",
+			code: `
+				// @server path=/users/{id}/{id} method=GET
+				func GetUser(id int) {}
+			`,
+			expectedParams: []types.ParamInfo{
+				{Name: "id", Type: "int", InPath: true},
+			},
+			expectedErrs: []string{
+				"GetUser: path /users/{id}/{id} uses wildcard {id} more than once",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vp, decl := parseFunctionCode(t, tt.code)
+			fn := vp.parseFunction(decl, "testpkg")
+
+			var errs []string
+			for _, d := range vp.diagnostics {
+				errs = append(errs, d.Message)
+			}
+
+			assert.Equal(t, tt.expectedParams, fn.Params)
+			assert.Equal(t, tt.expectedErrs, errs)
+		})
+	}
+}
+
 func TestParseFunctions(t *testing.T) {
 	tests := []struct {
-		name       string
-		code       string
-		structsMap types.DeclarationMap
-		expected   []types.FunctionInfo
+		name     string
+		code     string
+		expected []types.FunctionInfo
 	}{
 		{
 			name: "No functions in file",
 			code: `
 				var x = 10
 			`,
-			structsMap: types.DeclarationMap{},
-			expected:   nil,
+			expected: nil,
 		},
 		{
 			name: "Single annotated function",
@@ -717,17 +1149,17 @@ func TestParseFunctions(t *testing.T) {
 				// @server path=/hello method=GET
 				func SayHello() string { return "hi" }
 			`,
-			structsMap: types.DeclarationMap{},
 			expected: []types.FunctionInfo{
 				{
-					Name:        "SayHello",
-					Path:        "/hello",
-					Method:      "GET",
-					Params:      nil,
-					ReturnType:  "string",
-					IsSlice:     false,
-					IsMethod:    false,
-					PackageName: "testpkg",
+					Name:         "SayHello",
+					Path:         "/hello",
+					Method:       "GET",
+					Params:       nil,
+					Returns:      []types.ReturnInfo{{Type: "string"}},
+					IsMethod:     false,
+					PackageName:  "testpkg",
+					PackageAlias: "testpkg",
+					ImportPath:   "example.com/app/testpkg",
 				},
 			},
 		},
@@ -739,7 +1171,6 @@ func TestParseFunctions(t *testing.T) {
 				// @server path=/data method=POST
 				func SaveData(data string) error { return nil }
 			`,
-			structsMap: types.DeclarationMap{},
 			expected: []types.FunctionInfo{
 				{
 					Name:   "SaveData",
@@ -748,10 +1179,11 @@ func TestParseFunctions(t *testing.T) {
 					Params: []types.ParamInfo{
 						{Name: "data", Type: "string"},
 					},
-					ReturnType:  "error",
-					IsSlice:     false,
-					IsMethod:    false,
-					PackageName: "testpkg",
+					ReturnsError: true,
+					IsMethod:     false,
+					PackageName:  "testpkg",
+					PackageAlias: "testpkg",
+					ImportPath:   "example.com/app/testpkg",
 				},
 			},
 		},
@@ -762,19 +1194,19 @@ func TestParseFunctions(t *testing.T) {
 				// @server path=/status method=GET
 				func (s *Service) Status() string { return "ok" }
 			`,
-			structsMap: types.DeclarationMap{"Service": "testpkg"},
 			expected: []types.FunctionInfo{
 				{
 					Name:             "Status",
 					Path:             "/status",
 					Method:           "GET",
 					Params:           nil,
-					ReturnType:       "string",
-					IsSlice:          false,
+					Returns:          []types.ReturnInfo{{Type: "string"}},
 					IsMethod:         true,
 					ReceiverTypeName: "*testpkg.Service",
 					StructName:       "Service",
 					PackageName:      "testpkg",
+					PackageAlias:     "testpkg",
+					ImportPath:       "example.com/app/testpkg",
 				},
 			},
 		},
@@ -787,64 +1219,102 @@ func TestParseFunctions(t *testing.T) {
 				// @server path=/two method=GET
 				func Two() int { return 2 }
 			`,
-			structsMap: types.DeclarationMap{},
 			expected: []types.FunctionInfo{
 				{
-					Name:        "One",
-					Path:        "/one",
-					Method:      "GET",
-					ReturnType:  "int",
-					IsSlice:     false,
-					IsMethod:    false,
-					PackageName: "testpkg",
+					Name:         "One",
+					Path:         "/one",
+					Method:       "GET",
+					Returns:      []types.ReturnInfo{{Type: "int"}},
+					IsMethod:     false,
+					PackageName:  "testpkg",
+					PackageAlias: "testpkg",
+					ImportPath:   "example.com/app/testpkg",
 				},
 				{
-					Name:        "Two",
-					Path:        "/two",
-					Method:      "GET",
-					ReturnType:  "int",
-					IsSlice:     false,
-					IsMethod:    false,
-					PackageName: "testpkg",
+					Name:         "Two",
+					Path:         "/two",
+					Method:       "GET",
+					Returns:      []types.ReturnInfo{{Type: "int"}},
+					IsMethod:     false,
+					PackageName:  "testpkg",
+					PackageAlias: "testpkg",
+					ImportPath:   "example.com/app/testpkg",
 				},
 			},
 		},
 	}
 
-	vp := &VertexParser{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fset := token.NewFileSet()
-			node, err := parser.ParseFile(fset, "test.go", "package testpkg\n"+tt.code, parser.ParseComments)
-			require.NoError(t, err)
+			pkg := loadTestPackage(t, token.NewFileSet(), "example.com/app/testpkg", testFile{"test.go", "package testpkg\n" + tt.code})
+			vp := NewVertexParser(nil, []*packages.Package{pkg}, config.Config{})
 
-			result := vp.parseFunctions(node, tt.structsMap)
+			result := vp.parseFunctions(pkg, pkg.Syntax[0])
 
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
-func parseFunctionCode(t *testing.T, code string) *ast.FuncDecl {
+// testFile is a source file of a package type-checked by loadTestPackage.
+type testFile struct {
+	name string
+	src  string
+}
+
+// loadTestPackage type-checks the files of the package at importPath the way
+// packages.Load does. Type errors are recorded on the package rather than
+// failing the test.
+func loadTestPackage(t *testing.T, fset *token.FileSet, importPath string, files ...testFile) *packages.Package {
 	t.Helper()
 
-	node := parseGoFile(t, "package main\n"+code)
-	for _, decl := range node.Decls {
-		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-			return funcDecl
-		}
+	pkg := &packages.Package{
+		PkgPath: importPath,
+		Fset:    fset,
+		TypesInfo: &gotypes.Info{
+			Types: make(map[ast.Expr]gotypes.TypeAndValue),
+			Defs:  make(map[*ast.Ident]gotypes.Object),
+			Uses:  make(map[*ast.Ident]gotypes.Object),
+		},
+	}
+	for _, f := range files {
+		node, err := parser.ParseFile(fset, f.name, f.src, parser.ParseComments)
+		require.NoError(t, err, "Failed to parse Go code")
+		pkg.Syntax = append(pkg.Syntax, node)
 	}
 
-	t.Fatalf("Could not find function declaration in code")
-	return nil
+	conf := gotypes.Config{
+		Importer: importer.Default(),
+		Error: func(err error) {
+			pkg.Errors = append(pkg.Errors, packages.Error{Pos: fset.Position(err.(gotypes.Error).Pos).String(), Msg: err.(gotypes.Error).Msg, Kind: packages.TypeError})
+		},
+	}
+	pkg.Types, _ = conf.Check(importPath, fset, pkg.Syntax, pkg.TypesInfo)
+	pkg.Name = pkg.Types.Name()
+
+	return pkg
 }
 
-func parseGoFile(t *testing.T, src string) *ast.File {
+// parseFunctionCode type-checks code as a package example.com/app/testpkg,
+// adding the standard library imports it uses, and returns a parser for it
+// together with its first function declaration.
+func parseFunctionCode(t *testing.T, code string) (*VertexParser, *ast.FuncDecl) {
 	t.Helper()
 
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
-	assert.NoError(t, err, "Failed to parse Go code")
+	src, err := imports.Process("test.go", []byte("package testpkg\n"+code), nil)
+	require.NoError(t, err)
 
-	return node
+	pkg := loadTestPackage(t, token.NewFileSet(), "example.com/app/testpkg", testFile{"test.go", string(src)})
+	require.Empty(t, pkg.Errors)
+
+	vp := NewVertexParser(nil, []*packages.Package{pkg}, config.Config{})
+	vp.resolvePackages()
+	for _, decl := range pkg.Syntax[0].Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			return vp, funcDecl
+		}
+	}
+
+	t.Fatalf("Could not find function declaration in code")
+	return nil, nil
 }
@@ -1,26 +1,69 @@
 package types
 
+import "go/token"
+
 type ParamInfo struct {
-	Name string
+	Name   string
+	Type   string
+	InPath bool
+
+	// Ident names the variable holding the parameter in the generated code,
+	// which differs from Name when Name would hide another identifier.
+	Ident string
+
+	// Field names the exported field holding the parameter in the request
+	// struct, unique within the function. Name stays the JSON key.
+	Field string
+}
+
+type ReturnInfo struct {
 	Type string
 }
 
 type FunctionInfo struct {
+	Pos              token.Position
 	Name             string
+	Ident            string
 	Path             string
 	Method           string
 	Params           []ParamInfo
-	ReturnType       string
-	IsSlice          bool
+	HasContext       bool
+	Returns          []ReturnInfo
+	ReturnsError     bool
 	IsMethod         bool
 	ReceiverTypeName string
 	StructName       string
 	PackageName      string
+	PackageAlias     string
+	ImportPath       string
+	Middleware       []string
+	TypeArgs         []string
+}
+
+type Package struct {
+	Name       string
+	Alias      string
+	ImportPath string
+}
+
+type Constructor struct {
+	Name         string
+	Params       []ParamInfo
+	HasContext   bool
+	ReturnsValue bool
+	ReturnsError bool
+}
+
+type Service struct {
+	PackageAlias string
+	StructName   string
+	Constructor  *Constructor
+	HasFields    bool
 }
 
 type Vertex struct {
 	Functions    []FunctionInfo
+	Packages     []Package
+	Services     []Service
 	GoModPackage string
 }
-
-type DeclarationMap map[string]string
@@ -1,135 +1,152 @@
 package utils
 
 import (
-	"fmt"
-	"go/ast"
-	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
 
-	"github.com/jackparsonss/vertex/internal/codegen/types"
+	"github.com/jackparsonss/vertex/internal/constants"
 )
 
-func GetTypeString(expr ast.Expr, typeMap types.DeclarationMap) string {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		prefix := ""
-		if packageName, ok := typeMap[t.Name]; ok {
-			prefix = packageName + "."
-		}
-		return prefix + t.Name
-	case *ast.StarExpr:
-		return "*" + GetTypeString(t.X, typeMap)
-	case *ast.SelectorExpr:
-		return GetTypeString(t.X, typeMap) + "." + t.Sel.Name
-	case *ast.ArrayType:
-		if t.Len == nil {
-			return "[]" + GetTypeString(t.Elt, typeMap)
-		}
+// IsErrorType reports whether t is the predeclared error type.
+func IsErrorType(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
 
-		if lit, ok := t.Len.(*ast.BasicLit); ok && lit.Kind == token.INT {
-			return "[" + lit.Value + "]" + GetTypeString(t.Elt, typeMap)
-		}
+// IsContextType reports whether t is context.Context.
+func IsContextType(t types.Type) bool {
+	return isNamed(t, "context", "Context")
+}
 
-		return "[" + GetTypeString(t.Len, typeMap) + "]" + GetTypeString(t.Elt, typeMap)
-	case *ast.MapType:
-		return "map[" + GetTypeString(t.Key, typeMap) + "]" + GetTypeString(t.Value, typeMap)
-	case *ast.StructType:
-		if t.Fields != nil && len(t.Fields.List) > 0 {
-			result := "struct{"
-			for i, field := range t.Fields.List {
-				if i > 0 {
-					result += "\n"
-				}
-
-				if len(field.Names) > 0 {
-					for j, name := range field.Names {
-						if j > 0 {
-							result += ", "
-						}
-						result += name.Name
-					}
-					result += " "
-				}
-
-				result += GetTypeString(field.Type, typeMap)
-
-				if field.Tag != nil {
-					result += " " + field.Tag.Value
-				}
-			}
-			result += "}"
-			return result
-		}
-		return "struct{}"
-	case *ast.FuncType:
-		return "func" + formatFuncSig(t, typeMap)
-	case *ast.InterfaceType:
-		if t.Methods != nil && len(t.Methods.List) > 0 {
-			result := "interface{"
-			for i, method := range t.Methods.List {
-				if i > 0 {
-					result += "\n"
-				}
-
-				if len(method.Names) > 0 {
-					result += method.Names[0].Name
-				}
-
-				if ft, ok := method.Type.(*ast.FuncType); ok {
-					result += formatFuncSig(ft, typeMap)
-				} else {
-					result += " " + GetTypeString(method.Type, typeMap)
-				}
-			}
-			result += "}"
-			return result
-		}
-		return "interface{}"
-	case *ast.Ellipsis:
-		return "..." + GetTypeString(t.Elt, typeMap)
-	case *ast.ChanType:
-		switch t.Dir {
-		case ast.SEND:
-			return "chan<- " + GetTypeString(t.Value, typeMap)
-		case ast.RECV:
-			return "<-chan " + GetTypeString(t.Value, typeMap)
-		default:
-			return "chan " + GetTypeString(t.Value, typeMap)
-		}
+var pathParamPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// IsQueryType reports whether a parameter of type t can be encoded in a
+// query string: booleans, strings, numbers, types implementing
+// encoding.TextMarshaler and encoding.TextUnmarshaler such as time.Time, and
+// pointers or slices of these.
+func IsQueryType(t types.Type) bool {
+	if isTextType(t) {
+		return true
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		return isQueryScalar(u.Elem())
+	case *types.Slice:
+		return isQueryScalar(u.Elem())
+	default:
+		return isQueryScalar(t)
+	}
+}
+
+// AllowedMethods lists the methods a @server directive may declare.
+var AllowedMethods = map[string]bool{
+	"GET":                true,
+	"POST":               true,
+	"PUT":                true,
+	"PATCH":              true,
+	"DELETE":             true,
+	"HEAD":               true,
+	constants.ANY_METHOD: true,
+}
+
+// MethodHasBody reports whether the parameters of an endpoint using the given
+// method are sent in a JSON request body rather than in the query string.
+func MethodHasBody(method string) bool {
+	switch method {
+	case "GET", "HEAD", "DELETE":
+		return false
 	default:
-		return fmt.Sprintf("%T", expr)
+		return true
 	}
 }
 
-func formatFuncSig(t *ast.FuncType, typeMap types.DeclarationMap) string {
-	result := "("
+// Route returns the net/http.ServeMux pattern an endpoint is registered
+// under. Endpoints accepting any method are registered by path alone.
+func Route(method, path string) string {
+	if method == constants.ANY_METHOD {
+		return path
+	}
+
+	return method + " " + path
+}
 
-	if t.Params != nil && len(t.Params.List) > 0 {
-		for i, param := range t.Params.List {
-			if i > 0 {
-				result += ", "
-			}
-			result += GetTypeString(param.Type, typeMap)
+// IsPathType reports whether a parameter of type t can be bound to a single
+// path segment.
+func IsPathType(t types.Type) bool {
+	return isQueryScalar(t)
+}
+
+// PathParams returns the names of the wildcards in a ServeMux path pattern,
+// such as id in /users/{id} or rest in /files/{rest...}.
+func PathParams(path string) []string {
+	var names []string
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		name := strings.TrimSuffix(match[1], "...")
+		if name == "$" {
+			continue
 		}
+
+		names = append(names, name)
 	}
 
-	result += ")"
+	return names
+}
 
-	if t.Results == nil || len(t.Results.List) == 0 {
-		return result
+func isQueryScalar(t types.Type) bool {
+	if isTextType(t) {
+		return true
 	}
 
-	if len(t.Results.List) == 1 && len(t.Results.List[0].Names) == 0 {
-		return result + " " + GetTypeString(t.Results.List[0].Type, typeMap)
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Kind() == types.Uintptr {
+		return false
 	}
 
-	result += " ("
-	for i, ret := range t.Results.List {
-		if i > 0 {
-			result += ", "
-		}
-		result += GetTypeString(ret.Type, typeMap)
+	return basic.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0
+}
+
+// isTextType reports whether values of type t encode themselves as text,
+// which the generated runtime prefers over their underlying kind.
+func isTextType(t types.Type) bool {
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return false
+	}
+
+	return hasMethod(t, "MarshalText") && hasMethod(types.NewPointer(t), "UnmarshalText")
+}
+
+// hasMethod reports whether the method set of t has a method with the given
+// name and the signature of encoding.TextMarshaler or TextUnmarshaler.
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+
+	sig := fn.Signature()
+	switch name {
+	case "MarshalText":
+		return sig.Params().Len() == 0 && sig.Results().Len() == 2 && isBytes(sig.Results().At(0).Type()) && IsErrorType(sig.Results().At(1).Type())
+	default:
+		return sig.Params().Len() == 1 && sig.Results().Len() == 1 && isBytes(sig.Params().At(0).Type()) && IsErrorType(sig.Results().At(0).Type())
+	}
+}
+
+func isBytes(t types.Type) bool {
+	slice, ok := t.(*types.Slice)
+	return ok && types.Identical(slice.Elem(), types.Typ[types.Byte])
+}
+
+// isNamed reports whether t is the type declared as name in the package at
+// importPath.
+func isNamed(t types.Type, importPath, name string) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
 	}
-	result += ")"
 
-	return result
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == importPath && obj.Name() == name
 }
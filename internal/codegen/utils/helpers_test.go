@@ -2,195 +2,127 @@ package utils
 
 import (
 	"go/ast"
+	"go/importer"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"testing"
 
-	"github.com/jackparsonss/vertex/internal/codegen/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestGetTypeString(t *testing.T) {
+// typeOf type-checks the declarations and returns the type of variable x.
+func typeOf(t *testing.T, decls string) types.Type {
+	t.Helper()
+
+	src := `package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var _ = context.Background
+var _ fmt.Stringer
+var _ time.Time
+
+type UserID int
+
+type Status string
+
+type Point struct{ X, Y int }
+
+type Version struct{ Major, Minor int }
+
+func (v Version) MarshalText() ([]byte, error) { return nil, nil }
+
+func (v *Version) UnmarshalText(text []byte) error { return nil }
+
+` + decls
+
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, "test.go", src, 0)
+	require.NoError(t, err)
+
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("main", fset, []*ast.File{node}, nil)
+	require.NoError(t, err)
+
+	return pkg.Scope().Lookup("x").Type()
+}
+
+func TestIsQueryType(t *testing.T) {
 	tests := []struct {
-		name     string
 		code     string
-		typeMap  types.DeclarationMap
-		expected string
+		expected bool
 	}{
-		{
-			name:     "Identifier",
-			code:     "var x int",
-			typeMap:  types.DeclarationMap{},
-			expected: "int",
-		},
-		{
-			name:     "Default case - FuncType",
-			code:     "var x func(int) string",
-			typeMap:  types.DeclarationMap{},
-			expected: "func(int) string",
-		},
-		{
-			name:     "Default case - FuncType",
-			code:     "var x func(int, string) (string, bool)",
-			typeMap:  types.DeclarationMap{},
-			expected: "func(int, string) (string, bool)",
-		},
-		{
-			name:     "Default case - FuncType",
-			code:     "var x func(...string)",
-			typeMap:  types.DeclarationMap{},
-			expected: "func(...string)",
-		},
-		{
-			name:     "Default case - channel",
-			code:     "var x func(ch chan float)",
-			typeMap:  types.DeclarationMap{},
-			expected: "func(chan float)",
-		},
-		{
-			name:     "Default case - in channel",
-			code:     "var x func(ch <-chan float)",
-			typeMap:  types.DeclarationMap{},
-			expected: "func(<-chan float)",
-		},
-		{
-			name:     "Default case - out channel",
-			code:     "var x func(ch chan<- float)",
-			typeMap:  types.DeclarationMap{},
-			expected: "func(chan<- float)",
-		},
-		{
-			name:     "Identifier with package prefix",
-			code:     "var x CustomType",
-			typeMap:  types.DeclarationMap{"CustomType": "mypackage"},
-			expected: "mypackage.CustomType",
-		},
-		{
-			name:     "Pointer type",
-			code:     "var x *string",
-			typeMap:  types.DeclarationMap{},
-			expected: "*string",
-		},
-		{
-			name:     "Pointer to custom type with package",
-			code:     "var x *CustomType",
-			typeMap:  types.DeclarationMap{"CustomType": "mypackage"},
-			expected: "*mypackage.CustomType",
-		},
-		{
-			name:     "Selector Expression",
-			code:     "var x fmt.Stringer",
-			typeMap:  types.DeclarationMap{},
-			expected: "fmt.Stringer",
-		},
-		{
-			name:     "Array type",
-			code:     "var x []int",
-			typeMap:  types.DeclarationMap{},
-			expected: "[]int",
-		},
-		{
-			name:     "Array type",
-			code:     "var x [3]int",
-			typeMap:  types.DeclarationMap{},
-			expected: "[3]int",
-		},
-		{
-			name:     "Array of custom type with package",
-			code:     "var x []CustomType",
-			typeMap:  types.DeclarationMap{"CustomType": "mypackage"},
-			expected: "[]mypackage.CustomType",
-		},
-		{
-			name:     "Map type",
-			code:     "var x map[string]int",
-			typeMap:  types.DeclarationMap{},
-			expected: "map[string]int",
-		},
-		{
-			name:     "Map with custom types and packages",
-			code:     "var x map[KeyType]ValueType",
-			typeMap:  types.DeclarationMap{"KeyType": "pkg1", "ValueType": "pkg2"},
-			expected: "map[pkg1.KeyType]pkg2.ValueType",
-		},
-		{
-			name:     "Struct type",
-			code:     "var x struct{}",
-			typeMap:  types.DeclarationMap{},
-			expected: "struct{}",
-		},
-		{
-			name:     "Complex Struct type",
-			code:     "var x struct{Name string\nFoo int}",
-			typeMap:  types.DeclarationMap{},
-			expected: "struct{Name string\nFoo int}",
-		},
-		{
-			name:     "Interface type",
-			code:     "var x interface{}",
-			typeMap:  types.DeclarationMap{},
-			expected: "interface{}",
-		},
-		{
-			name:     "Complex Interface type",
-			code:     "var x interface{Name() string\nFoo() int}",
-			typeMap:  types.DeclarationMap{},
-			expected: "interface{Name() string\nFoo() int}",
-		},
-		{
-			name:     "Complex Type - Pointer to Array of Maps",
-			code:     "var x *[]map[string]int",
-			typeMap:  types.DeclarationMap{},
-			expected: "*[]map[string]int",
-		},
-		{
-			name:     "Complex Type with custom types",
-			code:     "var x *[]map[KeyType]ValueType",
-			typeMap:  types.DeclarationMap{"KeyType": "pkg1", "ValueType": "pkg2"},
-			expected: "*[]map[pkg1.KeyType]pkg2.ValueType",
-		},
+		{code: "var x string", expected: true},
+		{code: "var x bool", expected: true},
+		{code: "var x uint16", expected: true},
+		{code: "var x float32", expected: true},
+		{code: "var x time.Time", expected: true},
+		{code: "var x time.Duration", expected: true},
+		{code: "var x UserID", expected: true},
+		{code: "var x Status", expected: true},
+		{code: "var x Version", expected: true},
+		{code: "var x []Version", expected: true},
+		{code: "var x *int", expected: true},
+		{code: "var x []string", expected: true},
+		{code: "var x []int", expected: true},
+		{code: "var x any", expected: false},
+		{code: "var x error", expected: false},
+		{code: "var x fmt.Stringer", expected: false},
+		{code: "var x Point", expected: false},
+		{code: "var x complex128", expected: false},
+		{code: "var x uintptr", expected: false},
+		{code: "var x map[string]int", expected: false},
+		{code: "var x [][]string", expected: false},
+		{code: "var x [3]int", expected: false},
+		{code: "var x chan int", expected: false},
+		{code: "var x struct{ A int }", expected: false},
+		{code: "var x interface{}", expected: false},
+		{code: "var x func()", expected: false},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			expr := parseTypeExpr(t, tt.code)
-			result := GetTypeString(expr, tt.typeMap)
-			assert.Equal(t, tt.expected, result)
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsQueryType(typeOf(t, tt.code)))
 		})
 	}
 }
 
-func TestGetTypeString_DefaultCase(t *testing.T) {
-	expr := &ast.BasicLit{
-		Kind:  token.STRING,
-		Value: `"hello"`,
-	}
-	typeMap := types.DeclarationMap{}
-
-	result := GetTypeString(expr, typeMap)
-
-	assert.Equal(t, "*ast.BasicLit", result)
+func TestIsPathType(t *testing.T) {
+	assert.True(t, IsPathType(typeOf(t, "var x UserID")))
+	assert.True(t, IsPathType(typeOf(t, "var x Version")))
+	assert.False(t, IsPathType(typeOf(t, "var x []string")))
+	assert.False(t, IsPathType(typeOf(t, "var x *int")))
 }
 
-func parseTypeExpr(t *testing.T, code string) ast.Expr {
-	t.Helper()
+func TestIsContextType(t *testing.T) {
+	assert.True(t, IsContextType(typeOf(t, "var x context.Context")))
+	assert.True(t, IsContextType(typeOf(t, "type Ctx = context.Context\nvar x Ctx")))
+	assert.False(t, IsContextType(typeOf(t, "type Context interface{}\nvar x Context")))
+	assert.True(t, IsErrorType(typeOf(t, "var x error")))
+	assert.False(t, IsErrorType(typeOf(t, "var x fmt.Stringer")))
+}
 
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, "test.go", "package main\n"+code, 0)
-	if err != nil {
-		t.Fatalf("Failed to parse code: %v", err)
+func TestPathParams(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{path: "/api/users", expected: nil},
+		{path: "/api/users/{id}", expected: []string{"id"}},
+		{path: "/api/users/{id}/posts/{slug}", expected: []string{"id", "slug"}},
+		{path: "/files/{path...}", expected: []string{"path"}},
+		{path: "/api/{$}", expected: nil},
 	}
 
-	for _, decl := range node.Decls {
-		if genDecl, ok := decl.(*ast.GenDecl); ok {
-			for _, spec := range genDecl.Specs {
-				if valSpec, ok := spec.(*ast.ValueSpec); ok && valSpec.Type != nil {
-					return valSpec.Type
-				}
-			}
-		}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PathParams(tt.path))
+		})
 	}
-
-	t.Fatalf("Could not find type expression in code: %s", code)
-	return nil
 }
@@ -1,37 +1,239 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"go/token"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jackparsonss/vertex/internal/codegen/utils"
+	"github.com/jackparsonss/vertex/internal/constants"
 )
 
 type Config struct {
-	InputDir          string
+	InputRoots        []string
+	Include           []string
+	Exclude           []string
+	Tags              []string
 	OutputDir         string
 	PackageNameOutput string
 	GoModFile         string
+	ServerAddr        string
+	ClientBaseURL     string
+	DefaultMethod     string
+	Generators        []string
 }
 
-func NewConfig(outputDir, packageNameOutput string) (Config, error) {
-	absInputFile, err := filepath.Abs(".")
-	if err != nil {
-		return Config{}, fmt.Errorf("error resolving input file path :%v", err)
+var generators = []string{constants.SERVER_GENERATOR, constants.CLIENT_GENERATOR, constants.MAIN_GENERATOR}
+
+// Load reads the config file named by flags, or vertex.yaml when it exists,
+// overrides it with the flags and validates the result.
+func Load(flags Flags) (Config, error) {
+	file := flags.ConfigFile
+	if file == "" {
+		file = constants.CONFIG_FILE
+	}
+
+	settings, err := LoadSettings(file)
+	if err != nil && (flags.ConfigFile != "" || !errors.Is(err, fs.ErrNotExist)) {
+		return Config{}, err
+	}
+
+	flags.Apply(&settings)
+	return NewConfig(settings)
+}
+
+// NewConfig fills in the defaults of the settings left empty and validates
+// them, pointing errors at the file line or flag that set the offending key.
+func NewConfig(s Settings) (Config, error) {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s: %s", s.source(key), key, fmt.Sprintf(format, args...)))
+	}
+
+	roots := s.InputRoots
+	if len(roots) == 0 {
+		roots = []string{constants.DEFAULT_INPUT_ROOT}
+	}
+	var absRoots []string
+	for _, root := range roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return Config{}, fmt.Errorf("error resolving input root path :%v", err)
+		}
+
+		if info, err := os.Stat(absRoot); err != nil || !info.IsDir() {
+			invalid("input.roots", "%s is not a directory", root)
+		}
+		absRoots = append(absRoots, absRoot)
+	}
+
+	for _, globs := range []struct {
+		key      string
+		patterns []string
+	}{{"input.include", s.Include}, {"input.exclude", s.Exclude}} {
+		for _, pattern := range globs.patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				invalid(globs.key, "invalid glob %q", pattern)
+			}
+		}
 	}
 
+	for _, tag := range s.Tags {
+		if !validTag(tag) {
+			invalid("input.tags", "%q is not a valid build tag", tag)
+		}
+	}
+
+	outputDir := orDefault(s.OutputDir, constants.DEFAULT_OUTPUT_DIR)
 	absOutputDir, err := filepath.Abs(outputDir)
 	if err != nil {
 		return Config{}, fmt.Errorf("error resolving output directory path :%v", err)
 	}
 
+	packageName := orDefault(s.OutputPackage, constants.DEFAULT_OUTPUT_PACKAGE)
+	if !token.IsIdentifier(packageName) || packageName == "main" || packageName == "_" {
+		invalid("output.package", "%q is not a valid package name", packageName)
+	}
+
+	addr := orDefault(s.ServerAddr, constants.DEFAULT_SERVER_ADDR)
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		invalid("server.addr", "%v", err)
+	}
+
+	baseURL := s.ClientBaseURL
+	switch {
+	case baseURL != "":
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("client.base_url", "%q is not an http or https URL", baseURL)
+		}
+	case err == nil:
+		if host == "" || net.ParseIP(host).IsUnspecified() {
+			host = "localhost"
+		}
+		baseURL = "http://" + net.JoinHostPort(host, port)
+	}
+
+	method := strings.ToUpper(orDefault(s.DefaultMethod, constants.DEFAULT_METHOD))
+	if !utils.AllowedMethods[method] {
+		invalid("default_method", "unsupported method %s", s.DefaultMethod)
+	}
+
+	enabled := s.Generators
+	if len(enabled) == 0 {
+		enabled = generators
+	}
+	for _, generator := range enabled {
+		if !slices.Contains(generators, generator) {
+			invalid("generators", "unknown generator %q, expected %s", generator, strings.Join(generators, ", "))
+		}
+	}
+	if slices.Contains(enabled, constants.MAIN_GENERATOR) && !slices.Contains(enabled, constants.SERVER_GENERATOR) {
+		invalid("generators", "the %s generator requires the %s generator", constants.MAIN_GENERATOR, constants.SERVER_GENERATOR)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+
 	goModFile, err := filepath.Abs("go.mod")
 	if err != nil {
 		return Config{}, fmt.Errorf("error resolving go.mod file path :%v", err)
 	}
 
 	return Config{
-		InputDir:          absInputFile,
+		InputRoots:        absRoots,
+		Include:           s.Include,
+		Exclude:           s.Exclude,
+		Tags:              s.Tags,
 		OutputDir:         absOutputDir,
-		PackageNameOutput: packageNameOutput,
+		PackageNameOutput: packageName,
 		GoModFile:         goModFile,
+		ServerAddr:        addr,
+		ClientBaseURL:     baseURL,
+		DefaultMethod:     method,
+		Generators:        enabled,
 	}, nil
 }
+
+// Generates reports whether the given generator is enabled.
+func (c Config) Generates(generator string) bool {
+	return slices.Contains(c.Generators, generator)
+}
+
+// Excluded reports whether a file or directory, given by its path relative
+// to the module root, matches one of the exclude globs. A glob matches the
+// whole path or its last element.
+func (c Config) Excluded(rel string) bool {
+	return matches(c.Exclude, rel)
+}
+
+// Included reports whether a file, given by its path relative to the module
+// root, is scanned: when include globs are set, the file or one of its
+// parent directories must match one of them.
+func (c Config) Included(rel string) bool {
+	if len(c.Include) == 0 {
+		return true
+	}
+
+	for ; rel != "." && rel != string(filepath.Separator); rel = filepath.Dir(rel) {
+		if matches(c.Include, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OutputExcluded reports whether dir, an absolute directory, is or is below
+// the output directory, whose generated code is never scanned.
+func (c Config) OutputExcluded(dir string) bool {
+	rel, err := filepath.Rel(c.OutputDir, dir)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func matches(patterns []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validTag reports whether tag can be satisfied in a build constraint.
+func validTag(tag string) bool {
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			return false
+		}
+	}
+
+	return tag != ""
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "vertex.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadSettings(t *testing.T) {
+	path := writeConfig(t, `input:
+  roots: [services, /abs/api]
+  include: [api]
+  exclude: ["*_gen.go", "internal/legacy"]
+  tags: [pro, linux]
+output:
+  dir: gen/api
+  package: api
+server:
+  addr: ":9000"
+client:
+  base_url: https://api.example.com
+default_method: get
+generators: [server, client]
+`)
+	dir := filepath.Dir(path)
+
+	s, err := LoadSettings(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{filepath.Join(dir, "services"), "/abs/api"}, s.InputRoots)
+	assert.Equal(t, []string{"api"}, s.Include)
+	assert.Equal(t, []string{"*_gen.go", "internal/legacy"}, s.Exclude)
+	assert.Equal(t, []string{"pro", "linux"}, s.Tags)
+	assert.Equal(t, filepath.Join(dir, "gen/api"), s.OutputDir)
+	assert.Equal(t, "api", s.OutputPackage)
+	assert.Equal(t, ":9000", s.ServerAddr)
+	assert.Equal(t, "https://api.example.com", s.ClientBaseURL)
+	assert.Equal(t, "get", s.DefaultMethod)
+	assert.Equal(t, []string{"server", "client"}, s.Generators)
+	assert.Equal(t, path+":8", s.source("output.package"))
+	assert.Equal(t, "default", s.source("unset"))
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "Unknown key",
+			content:  "output:\n  dir: gen\n  pkg: api\n",
+			expected: ":3: unknown key output.pkg",
+		},
+		{
+			name:     "Unknown section",
+			content:  "database:\n  url: postgres://\n",
+			expected: ":1: unknown key database",
+		},
+		{
+			name:     "Wrong type",
+			content:  "generators: server\n",
+			expected: ":1: generators: expected a list of strings",
+		},
+		{
+			name:     "Section is not a mapping",
+			content:  "server: \":8080\"\n",
+			expected: ":1: server: expected a mapping of settings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.content)
+
+			_, err := LoadSettings(path)
+			assert.EqualError(t, err, path+tt.expected)
+		})
+	}
+}
+
+func TestFlags(t *testing.T) {
+	var flags Flags
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.Register(fs)
+	require.NoError(t, fs.Parse([]string{"-config", "api.yaml", "-pkg", "api", "-generators", "server, main"}))
+
+	s := Settings{OutputPackage: "fromfile", ServerAddr: ":9000"}
+	flags.Apply(&s)
+
+	assert.Equal(t, "api.yaml", flags.ConfigFile)
+	assert.Equal(t, "api", s.OutputPackage)
+	assert.Equal(t, ":9000", s.ServerAddr)
+	assert.Equal(t, []string{"server", "main"}, s.Generators)
+	assert.Equal(t, "-pkg", s.source("output.package"))
+}
+
+func TestNewConfig(t *testing.T) {
+	c, err := NewConfig(Settings{ServerAddr: "127.0.0.1:9000", DefaultMethod: "get"})
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, []string{wd}, c.InputRoots)
+	assert.Equal(t, filepath.Join(wd, "vertex"), c.OutputDir)
+	assert.Equal(t, "vertex", c.PackageNameOutput)
+	assert.Equal(t, "http://127.0.0.1:9000", c.ClientBaseURL)
+	assert.Equal(t, "GET", c.DefaultMethod)
+	assert.True(t, c.Generates("main"))
+
+	c, err = NewConfig(Settings{})
+	require.NoError(t, err)
+	assert.Equal(t, ":8080", c.ServerAddr)
+	assert.Equal(t, "http://localhost:8080", c.ClientBaseURL)
+}
+
+func TestNewConfigErrors(t *testing.T) {
+	s := Settings{
+		InputRoots:    []string{"does-not-exist"},
+		Include:       []string{"api/[a"},
+		Exclude:       []string{"[gen"},
+		Tags:          []string{"pro", "!linux"},
+		OutputPackage: "my-api",
+		ServerAddr:    "8080",
+		ClientBaseURL: "localhost:8080",
+		DefaultMethod: "FETCH",
+		Generators:    []string{"client", "main", "docs"},
+	}
+	for _, key := range []string{"input.roots", "input.include", "input.exclude", "input.tags", "output.package", "server.addr", "client.base_url", "default_method"} {
+		s.setSource(key, "vertex.yaml:1")
+	}
+	s.setSource("generators", "-generators")
+
+	_, err := NewConfig(s)
+	assert.EqualError(t, err, `vertex.yaml:1: input.roots: does-not-exist is not a directory
+vertex.yaml:1: input.include: invalid glob "api/[a"
+vertex.yaml:1: input.exclude: invalid glob "[gen"
+vertex.yaml:1: input.tags: "!linux" is not a valid build tag
+vertex.yaml:1: output.package: "my-api" is not a valid package name
+vertex.yaml:1: server.addr: address 8080: missing port in address
+vertex.yaml:1: client.base_url: "localhost:8080" is not an http or https URL
+vertex.yaml:1: default_method: unsupported method FETCH
+-generators: generators: unknown generator "docs", expected server, client, main
+-generators: generators: the main generator requires the server generator`)
+
+	s = Settings{ServerAddr: "8080"}
+	s.setSource("server.addr", "-addr")
+	_, err = NewConfig(s)
+	assert.EqualError(t, err, "-addr: server.addr: address 8080: missing port in address", "an unset base URL is not reported")
+}
+
+func TestExcluded(t *testing.T) {
+	c := Config{Exclude: []string{"*_gen.go", "internal/legacy", "testdata"}}
+
+	assert.True(t, c.Excluded("api/users_gen.go"))
+	assert.True(t, c.Excluded("internal/legacy"))
+	assert.True(t, c.Excluded("api/testdata"))
+	assert.False(t, c.Excluded("internal/billing"))
+	assert.False(t, c.Excluded("api/users.go"))
+}
+
+func TestIncluded(t *testing.T) {
+	assert.True(t, Config{}.Included("api/users.go"))
+
+	c := Config{Include: []string{"api", "*_api.go"}}
+
+	assert.True(t, c.Included("api/users.go"))
+	assert.True(t, c.Included("api/v2/users.go"))
+	assert.True(t, c.Included("billing/invoices_api.go"))
+	assert.False(t, c.Included("billing/invoices.go"))
+	assert.False(t, c.Included("apis/users.go"))
+}
+
+func TestOutputExcluded(t *testing.T) {
+	c := Config{OutputDir: "/app/vertex"}
+
+	assert.True(t, c.OutputExcluded("/app/vertex"))
+	assert.True(t, c.OutputExcluded("/app/vertex/sub"))
+	assert.False(t, c.OutputExcluded("/app/vertexes"))
+	assert.False(t, c.OutputExcluded("/app"))
+	assert.False(t, Config{}.OutputExcluded("/app/vertex"))
+}
+
+func TestExample(t *testing.T) {
+	s, err := LoadSettings(writeConfig(t, Example))
+	require.NoError(t, err)
+
+	c, err := NewConfig(s)
+	require.NoError(t, err)
+	assert.Equal(t, "vertex", c.PackageNameOutput)
+	assert.Equal(t, "http://localhost:8080", c.ClientBaseURL)
+	assert.Equal(t, []string{"server", "client", "main"}, c.Generators)
+}
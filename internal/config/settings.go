@@ -0,0 +1,305 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings holds the project settings read from the config file and
+// overridden by command line flags. Settings left empty use their defaults.
+type Settings struct {
+	InputRoots    []string
+	Include       []string
+	Exclude       []string
+	Tags          []string
+	OutputDir     string
+	OutputPackage string
+	ServerAddr    string
+	ClientBaseURL string
+	DefaultMethod string
+	Generators    []string
+
+	// sources records where each key was set, such as "vertex.yaml:3" or
+	// "-out", to point validation errors at it.
+	sources map[string]string
+}
+
+// setting describes a key of the config file and the flag overriding it.
+type setting struct {
+	key   string
+	flag  string
+	usage string
+	path  bool
+	field func(*Settings) any
+}
+
+var settings = []setting{
+	{
+		key: "input.roots", flag: "input", path: true,
+		usage: "comma-separated directories scanned for @server directives",
+		field: func(s *Settings) any { return &s.InputRoots },
+	},
+	{
+		key: "input.include", flag: "include",
+		usage: "comma-separated globs of files and directories to scan, all by default",
+		field: func(s *Settings) any { return &s.Include },
+	},
+	{
+		key: "input.exclude", flag: "exclude",
+		usage: "comma-separated globs of files and directories to skip",
+		field: func(s *Settings) any { return &s.Exclude },
+	},
+	{
+		key: "input.tags", flag: "tags",
+		usage: "comma-separated build tags considered satisfied when selecting files",
+		field: func(s *Settings) any { return &s.Tags },
+	},
+	{
+		key: "output.dir", flag: "out", path: true,
+		usage: "directory the package is generated in",
+		field: func(s *Settings) any { return &s.OutputDir },
+	},
+	{
+		key: "output.package", flag: "pkg",
+		usage: "name of the generated package",
+		field: func(s *Settings) any { return &s.OutputPackage },
+	},
+	{
+		key: "server.addr", flag: "addr",
+		usage: "address the generated server listens on by default",
+		field: func(s *Settings) any { return &s.ServerAddr },
+	},
+	{
+		key: "client.base_url", flag: "base-url",
+		usage: "URL the generated client reaches the server at by default",
+		field: func(s *Settings) any { return &s.ClientBaseURL },
+	},
+	{
+		key: "default_method", flag: "method",
+		usage: "method of @server directives that do not declare one",
+		field: func(s *Settings) any { return &s.DefaultMethod },
+	},
+	{
+		key: "generators", flag: "generators",
+		usage: "comma-separated generators to run: server, client and main",
+		field: func(s *Settings) any { return &s.Generators },
+	},
+}
+
+// LoadSettings reads the settings of a config file. Relative paths in the
+// file are relative to the directory holding it.
+func LoadSettings(path string) (Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return Settings{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var s Settings
+	if len(root.Content) == 0 {
+		return s, nil
+	}
+
+	errs := s.decode(path, "", root.Content[0])
+	return s, errors.Join(errs...)
+}
+
+// decode sets the settings held by a mapping of the config file, whose keys
+// are nested under prefix.
+func (s *Settings) decode(path, prefix string, node *yaml.Node) []error {
+	if node.Kind != yaml.MappingNode {
+		if prefix == "" {
+			return []error{fmt.Errorf("%s:%d: expected a mapping of settings", path, node.Line)}
+		}
+
+		return []error{fmt.Errorf("%s:%d: %s: expected a mapping of settings", path, node.Line, strings.TrimSuffix(prefix, "."))}
+	}
+
+	var errs []error
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, value := node.Content[i], node.Content[i+1]
+		key := prefix + keyNode.Value
+		source := fmt.Sprintf("%s:%d", path, keyNode.Line)
+
+		if setting, ok := lookupSetting(key); ok {
+			if err := value.Decode(setting.field(s)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: expected %s", source, key, setting.kind()))
+				continue
+			}
+
+			if setting.path {
+				setting.resolve(s, filepath.Dir(path))
+			}
+			s.setSource(key, source)
+			continue
+		}
+
+		if !hasSettings(key + ".") {
+			errs = append(errs, fmt.Errorf("%s: unknown key %s", source, key))
+			continue
+		}
+
+		errs = append(errs, s.decode(path, key+".", value)...)
+	}
+
+	return errs
+}
+
+func (s *Settings) setSource(key, source string) {
+	if s.sources == nil {
+		s.sources = make(map[string]string)
+	}
+
+	s.sources[key] = source
+}
+
+// source returns where a key was set, for error messages.
+func (s *Settings) source(key string) string {
+	if source, ok := s.sources[key]; ok {
+		return source
+	}
+
+	return "default"
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, setting := range settings {
+		if setting.key == key {
+			return setting, true
+		}
+	}
+
+	return setting{}, false
+}
+
+func hasSettings(prefix string) bool {
+	for _, setting := range settings {
+		if strings.HasPrefix(setting.key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// kind describes the value a setting expects.
+func (st setting) kind() string {
+	if _, ok := st.field(&Settings{}).(*[]string); ok {
+		return "a list of strings"
+	}
+
+	return "a string"
+}
+
+// resolve makes the relative paths of a setting relative to dir.
+func (st setting) resolve(s *Settings, dir string) {
+	switch field := st.field(s).(type) {
+	case *string:
+		if *field != "" && !filepath.IsAbs(*field) {
+			*field = filepath.Join(dir, *field)
+		}
+	case *[]string:
+		for i, path := range *field {
+			if !filepath.IsAbs(path) {
+				(*field)[i] = filepath.Join(dir, path)
+			}
+		}
+	}
+}
+
+// Flags collects the settings given on the command line, which take
+// precedence over the config file.
+type Flags struct {
+	// ConfigFile is the path of the config file, or empty to read
+	// vertex.yaml when it exists.
+	ConfigFile string
+
+	set []func(*Settings)
+}
+
+// Register defines the -config flag and a flag for every setting on fs.
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.ConfigFile, "config", "", "path of the config file (default vertex.yaml)")
+	for _, st := range settings {
+		fs.Func(st.flag, st.usage, func(value string) error {
+			f.set = append(f.set, func(s *Settings) {
+				switch field := st.field(s).(type) {
+				case *string:
+					*field = value
+				case *[]string:
+					*field = splitList(value)
+				}
+				s.setSource(st.key, "-"+st.flag)
+			})
+			return nil
+		})
+	}
+}
+
+// Apply overrides s with the settings given on the command line.
+func (f *Flags) Apply(s *Settings) {
+	for _, set := range f.set {
+		set(s)
+	}
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// Example is the config file written by vertex init. It lists every setting
+// with its default value.
+const Example = `# Settings of the vertex code generator. Every setting is optional and can be
+# overridden by the command line flag named next to it.
+
+input:
+  # -input: directories scanned for @server directives.
+  roots: [.]
+  # -include: globs of files and directories to scan, all when empty. Globs
+  # are matched against paths relative to the module root or their last
+  # element.
+  include: []
+  # -exclude: globs of files and directories to skip.
+  exclude: []
+  # -tags: build tags considered satisfied when selecting files. Test files
+  # and the vendor, testdata and output directories are never scanned.
+  tags: []
+
+output:
+  # -out: directory the package is generated in.
+  dir: vertex
+  # -pkg: name of the generated package.
+  package: vertex
+
+server:
+  # -addr: address the generated server listens on by default.
+  addr: ":8080"
+
+client:
+  # -base-url: URL the generated client reaches the server at by default,
+  # derived from server.addr when empty.
+  base_url: ""
+
+# -method: method of @server directives that do not declare one.
+default_method: POST
+
+# -generators: code to generate, among server, client and main.
+generators: [server, client, main]
+`
@@ -0,0 +1,14 @@
+package constants
+
+const (
+	CONFIG_FILE = "vertex.yaml"
+
+	DEFAULT_INPUT_ROOT     = "."
+	DEFAULT_OUTPUT_DIR     = "vertex"
+	DEFAULT_OUTPUT_PACKAGE = "vertex"
+	DEFAULT_SERVER_ADDR    = ":8080"
+
+	SERVER_GENERATOR = "server"
+	CLIENT_GENERATOR = "client"
+	MAIN_GENERATOR   = "main"
+)
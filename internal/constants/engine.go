@@ -1,7 +1,14 @@
 package constants
 
 const (
-	SERVER_DIRECTIVE = "@server"
-	PATH_DIRECTIVE   = "path="
-	METHOD_DIRECTIVE = "method="
+	SERVER_DIRECTIVE      = "@server"
+	PATH_DIRECTIVE        = "path="
+	METHOD_DIRECTIVE      = "method="
+	MIDDLEWARE_DIRECTIVE  = "middleware="
+	INSTANTIATE_DIRECTIVE = "instantiate="
+
+	GENERATED_HEADER = "// Code generated by vertex; DO NOT EDIT."
+
+	DEFAULT_METHOD = "POST"
+	ANY_METHOD     = "ANY"
 )